@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,6 +12,11 @@ type Config struct {
 	Database DatabaseConfig
 	JWT      JWTConfig
 	CORS     CORSConfig
+	Admin    AdminConfig
+	Auth     AuthConfig
+	Notify   NotifyConfig
+	Store    StoreConfig
+	OIDC     []OIDCProviderConfig
 	Env      string
 }
 
@@ -28,14 +35,126 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string
-	Expiry string
+	Secret        string
+	Expiry        string
+	RefreshExpiry string
+}
+
+// ExpiryDuration parses Expiry, falling back to 24 hours if it is invalid
+func (j JWTConfig) ExpiryDuration() time.Duration {
+	return parseDuration(j.Expiry, 24*time.Hour)
+}
+
+// RefreshExpiryDuration parses RefreshExpiry, falling back to 30 days if it is invalid
+func (j JWTConfig) RefreshExpiryDuration() time.Duration {
+	return parseDuration(j.RefreshExpiry, 30*24*time.Hour)
 }
 
 type CORSConfig struct {
 	Origin string
 }
 
+// AuthConfig holds account security settings
+type AuthConfig struct {
+	PasswordResetTTL     string
+	PasswordMinLength    int
+	PasswordMinClasses   int
+	PasswordDenylistFile string
+	LockoutThreshold     int
+	LockoutBaseDuration  string
+	LockoutMaxDuration   string
+	IPFailureLimit       int
+	IPFailureWindow      string
+	TOTPIssuer           string
+	TwoFactorTTL         string
+	EmailVerifyTTL       string
+	EmailResendInterval  string
+}
+
+// PasswordResetTTLDuration parses PasswordResetTTL, falling back to 30 minutes if it is invalid
+func (a AuthConfig) PasswordResetTTLDuration() time.Duration {
+	return parseDuration(a.PasswordResetTTL, 30*time.Minute)
+}
+
+// LockoutBase parses LockoutBaseDuration, falling back to 1 minute if it is invalid
+func (a AuthConfig) LockoutBase() time.Duration {
+	return parseDuration(a.LockoutBaseDuration, time.Minute)
+}
+
+// LockoutMax parses LockoutMaxDuration, falling back to 1 hour if it is invalid
+func (a AuthConfig) LockoutMax() time.Duration {
+	return parseDuration(a.LockoutMaxDuration, time.Hour)
+}
+
+// IPFailureWindowDuration parses IPFailureWindow, falling back to 15 minutes if it is invalid
+func (a AuthConfig) IPFailureWindowDuration() time.Duration {
+	return parseDuration(a.IPFailureWindow, 15*time.Minute)
+}
+
+// TwoFactorTTLDuration parses TwoFactorTTL, falling back to 5 minutes if it is invalid
+func (a AuthConfig) TwoFactorTTLDuration() time.Duration {
+	return parseDuration(a.TwoFactorTTL, 5*time.Minute)
+}
+
+// EmailVerifyTTLDuration parses EmailVerifyTTL, falling back to 24 hours if it is invalid
+func (a AuthConfig) EmailVerifyTTLDuration() time.Duration {
+	return parseDuration(a.EmailVerifyTTL, 24*time.Hour)
+}
+
+// EmailResendIntervalDuration parses EmailResendInterval, falling back to 1 minute if it is invalid
+func (a AuthConfig) EmailResendIntervalDuration() time.Duration {
+	return parseDuration(a.EmailResendInterval, time.Minute)
+}
+
+// NotifyConfig selects how user notifications are delivered
+type NotifyConfig struct {
+	Driver       string
+	FilePath     string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+}
+
+// StoreConfig holds catalog, pricing and inventory settings
+type StoreConfig struct {
+	Currency                 string
+	ReservationTTL           string
+	ReservationSweepInterval string
+	LegacyItemStock          int
+}
+
+// ReservationTTLDuration parses ReservationTTL, falling back to 15 minutes if it is invalid
+func (s StoreConfig) ReservationTTLDuration() time.Duration {
+	return parseDuration(s.ReservationTTL, 15*time.Minute)
+}
+
+// ReservationSweepIntervalDuration parses ReservationSweepInterval, falling back to 1 minute if it is invalid
+func (s StoreConfig) ReservationSweepIntervalDuration() time.Duration {
+	return parseDuration(s.ReservationSweepInterval, time.Minute)
+}
+
+// OIDCProviderConfig describes an external OpenID Connect identity provider.
+// Issuer is required; endpoints left empty are discovered from the issuer's openid-configuration.
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	JWKSURL      string
+	Scopes       []string
+}
+
+// AdminConfig holds the credentials used to bootstrap the first admin account
+type AdminConfig struct {
+	Username string
+	Password string
+}
+
 var AppConfig *Config
 
 func LoadConfig() {
@@ -53,16 +172,76 @@ func LoadConfig() {
 			Password: getEnv("DB_PASSWORD", ""),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key-here"),
-			Expiry: getEnv("JWT_EXPIRY", "24h"),
+			Secret:        getEnv("JWT_SECRET", "your-secret-key-here"),
+			Expiry:        getEnv("JWT_EXPIRY", "24h"),
+			RefreshExpiry: getEnv("JWT_REFRESH_EXPIRY", "720h"),
 		},
 		CORS: CORSConfig{
 			Origin: getEnv("CORS_ORIGIN", "*"),
 		},
-		Env: getEnv("ENV", "development"),
+		Admin: AdminConfig{
+			Username: getEnv("ADMIN_USERNAME", ""),
+			Password: getEnv("ADMIN_PASSWORD", ""),
+		},
+		Auth: AuthConfig{
+			PasswordResetTTL:     getEnv("PASSWORD_RESET_TTL", "30m"),
+			PasswordMinLength:    getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			PasswordMinClasses:   getEnvInt("PASSWORD_MIN_CLASSES", 2),
+			PasswordDenylistFile: getEnv("PASSWORD_DENYLIST_FILE", "common-passwords.txt"),
+			LockoutThreshold:     getEnvInt("LOCKOUT_THRESHOLD", 5),
+			LockoutBaseDuration:  getEnv("LOCKOUT_BASE_DURATION", "1m"),
+			LockoutMaxDuration:   getEnv("LOCKOUT_MAX_DURATION", "1h"),
+			IPFailureLimit:       getEnvInt("IP_FAILURE_LIMIT", 20),
+			IPFailureWindow:      getEnv("IP_FAILURE_WINDOW", "15m"),
+			TOTPIssuer:           getEnv("TOTP_ISSUER", "E-Commerce App"),
+			TwoFactorTTL:         getEnv("TWO_FACTOR_TTL", "5m"),
+			EmailVerifyTTL:       getEnv("EMAIL_VERIFY_TTL", "24h"),
+			EmailResendInterval:  getEnv("EMAIL_RESEND_INTERVAL", "1m"),
+		},
+		Notify: NotifyConfig{
+			Driver:       getEnv("NOTIFY_DRIVER", "log"),
+			FilePath:     getEnv("NOTIFY_FILE", "notifications.log"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "1025"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:     getEnv("SMTP_FROM", "no-reply@localhost"),
+		},
+		Store: StoreConfig{
+			Currency:                 strings.ToUpper(getEnv("STORE_CURRENCY", "USD")),
+			ReservationTTL:           getEnv("CART_RESERVATION_TTL", "15m"),
+			ReservationSweepInterval: getEnv("RESERVATION_SWEEP_INTERVAL", "1m"),
+			LegacyItemStock:          getEnvInt("LEGACY_ITEM_STOCK", 0),
+		},
+		OIDC: loadOIDCProviders(),
+		Env:  getEnv("ENV", "development"),
 	}
 }
 
+// loadOIDCProviders reads OIDC_PROVIDERS (comma separated names) and the OIDC_<NAME>_* settings for each
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			AuthURL:      getEnv(prefix+"AUTH_URL", ""),
+			TokenURL:     getEnv(prefix+"TOKEN_URL", ""),
+			JWKSURL:      getEnv(prefix+"JWKS_URL", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+	return providers
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -70,6 +249,14 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// parseDuration parses a positive duration string, returning fallback if it is invalid
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
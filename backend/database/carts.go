@@ -0,0 +1,79 @@
+package database
+
+import (
+	"strings"
+
+	"ecommerce-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ActiveCart returns the user's active cart, opening a new one when they have none (for example
+// after their last cart was converted into an order). The user's cart_id follows the active cart.
+func ActiveCart(db *gorm.DB, userID uint) (models.Cart, error) {
+	var cart models.Cart
+	err := db.Where("user_id = ? AND status = ?", userID, models.CartStatusActive).First(&cart).Error
+	if err == nil {
+		return cart, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return models.Cart{}, err
+	}
+
+	cart = models.Cart{
+		UserID: userID,
+		Name:   "My Cart",
+		Status: models.CartStatusActive,
+	}
+	if err := db.Create(&cart).Error; err != nil {
+		// A concurrent request may have opened the cart first
+		var existing models.Cart
+		if db.Where("user_id = ? AND status = ?", userID, models.CartStatusActive).First(&existing).Error == nil {
+			return existing, nil
+		}
+		return models.Cart{}, err
+	}
+	db.Model(&models.User{}).Where("id = ?", userID).Update("cart_id", cart.ID)
+	return cart, nil
+}
+
+// migrateCartOwnership drops the one-cart-per-user constraint from databases created before users
+// could keep converted carts, and enforces a single active cart per user instead
+func migrateCartOwnership() error {
+	if DB.Dialect().GetName() == "sqlite3" {
+		var schema string
+		DB.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'carts'").Row().Scan(&schema)
+		if strings.Contains(schema, `"user_id" integer NOT NULL UNIQUE`) {
+			// SQLite cannot drop a column constraint, so the table is rebuilt
+			tx := DB.Begin()
+			if err := rebuildCartsTable(tx); err != nil {
+				tx.Rollback()
+				return err
+			}
+			if err := tx.Commit().Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_carts_active_user ON carts(user_id) WHERE status = 'active'").Error
+}
+
+// rebuildCartsTable recreates carts from the current model and copies the existing rows across
+func rebuildCartsTable(tx *gorm.DB) error {
+	// Indexes move with the renamed table, so free their names for the new one
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_carts_user_id").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("ALTER TABLE carts RENAME TO carts_old").Error; err != nil {
+		return err
+	}
+	if err := tx.AutoMigrate(&models.Cart{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("INSERT INTO carts (id, user_id, name, status, created_at, updated_at) " +
+		"SELECT id, user_id, name, status, created_at, updated_at FROM carts_old").Error; err != nil {
+		return err
+	}
+	return tx.Exec("DROP TABLE carts_old").Error
+}
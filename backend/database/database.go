@@ -2,9 +2,11 @@ package database
 
 import (
 	"log"
+	"strings"
 
 	"ecommerce-backend/config"
 	"ecommerce-backend/models"
+	"ecommerce-backend/utils"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
@@ -19,6 +21,11 @@ func InitDatabase() {
 	// Use configuration for database connection
 	dbType := config.AppConfig.Database.Type
 	dbName := config.AppConfig.Database.Name
+
+	// Transactions take the write lock up front, and other writers wait for it rather than failing
+	if dbType == "sqlite3" && !strings.Contains(dbName, "?") {
+		dbName += "?_busy_timeout=5000&_txlock=immediate"
+	}
 	
 	DB, err = gorm.Open(dbType, dbName)
 	if err != nil {
@@ -28,6 +35,15 @@ func InitDatabase() {
 	// Enable GORM logging
 	DB.LogMode(true)
 
+	// Accounts created before email verification existed have no address to verify
+	grandfatherEmails := DB.HasTable(&models.User{}) && !DB.Dialect().HasColumn("users", "email_verified")
+
+	// Items created before stock was tracked need an opening stock booked for them
+	legacyItems := DB.HasTable(&models.Item{}) && !DB.Dialect().HasColumn("items", "stock")
+
+	// Orders placed before statuses were tracked would otherwise all appear to await payment
+	legacyOrders := DB.HasTable(&models.Order{}) && !DB.Dialect().HasColumn("orders", "status")
+
 	// Auto migrate the schema
 	err = DB.AutoMigrate(
 		&models.User{},
@@ -35,14 +51,53 @@ func InitDatabase() {
 		&models.Cart{},
 		&models.CartItem{},
 		&models.Order{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.Permission{},
+		&models.Role{},
+		&models.PasswordResetToken{},
+		&models.LoginAttempt{},
+		&models.RecoveryCode{},
+		&models.EmailVerificationToken{},
+		&models.LinkedIdentity{},
+		&models.OAuthState{},
+		&models.LoginChallenge{},
+		&models.APIKey{},
+		&models.Address{},
+		&models.StockAdjustment{},
+		&models.StockReservation{},
+		&models.OrderLine{},
+		&models.OrderStatusEvent{},
 	).Error
 
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	if grandfatherEmails {
+		if err := DB.Exec("UPDATE users SET email_verified = ?", true).Error; err != nil {
+			log.Fatal("Failed to migrate users:", err)
+		}
+		log.Println("Marked existing accounts as email verified")
+	}
+	if legacyItems {
+		if err := bookLegacyStock(config.AppConfig.Store.LegacyItemStock); err != nil {
+			log.Fatal("Failed to migrate item stock:", err)
+		}
+	}
+	if legacyOrders {
+		if err := markLegacyOrders(); err != nil {
+			log.Fatal("Failed to migrate orders:", err)
+		}
+	}
+	if err := migrateCartOwnership(); err != nil {
+		log.Fatal("Failed to migrate carts:", err)
+	}
 
 	// Seed initial data
 	seedInitialData()
+	seedRoles()
+	seedAdminUser()
 }
 
 // seedInitialData adds some initial items to the database
@@ -51,22 +106,76 @@ func seedInitialData() {
 	DB.Model(&models.Item{}).Count(&count)
 	
 	if count == 0 {
+		currency := config.AppConfig.Store.Currency
 		items := []models.Item{
-			{Name: "Laptop", Status: "available"},
-			{Name: "Smartphone", Status: "available"},
-			{Name: "Headphones", Status: "available"},
-			{Name: "Tablet", Status: "available"},
-			{Name: "Wireless Mouse", Status: "available"},
-			{Name: "Keyboard", Status: "available"},
-			{Name: "Monitor", Status: "available"},
-			{Name: "USB Cable", Status: "available"},
+			{Name: "Laptop", Price: models.Money{Amount: 99900, Currency: currency}},
+			{Name: "Smartphone", Price: models.Money{Amount: 69900, Currency: currency}},
+			{Name: "Headphones", Price: models.Money{Amount: 14900, Currency: currency}},
+			{Name: "Tablet", Price: models.Money{Amount: 44900, Currency: currency}},
+			{Name: "Wireless Mouse", Price: models.Money{Amount: 2999, Currency: currency}},
+			{Name: "Keyboard", Price: models.Money{Amount: 7999, Currency: currency}},
+			{Name: "Monitor", Price: models.Money{Amount: 24900, Currency: currency}},
+			{Name: "USB Cable", Price: models.Money{Amount: 999, Currency: currency}},
 		}
 
+		// Like created items, seeded items start empty and get their opening stock through the ledger
 		for _, item := range items {
+			item.Status = models.ItemStatusOutOfStock
 			DB.Create(&item)
+			AdjustStock(DB, item.ID, 25, StockChange{Reason: models.StockReasonRestock, Note: "Opening stock"})
 		}
 		log.Println("Initial items seeded successfully")
 	}
+
+	// Items created before pricing existed are priced at zero in the store currency
+	DB.Model(&models.Item{}).
+		Where("price_currency IS NULL OR price_currency = ''").
+		Updates(map[string]interface{}{"price_amount": 0, "price_currency": config.AppConfig.Store.Currency})
+
+	// Status follows stock, including for items created before stock was tracked
+	syncItemStatuses()
+}
+
+// seedAdminUser bootstraps the first admin from ADMIN_USERNAME/ADMIN_PASSWORD when no admin exists
+func seedAdminUser() {
+	username := config.AppConfig.Admin.Username
+	password := config.AppConfig.Admin.Password
+	if username == "" || password == "" {
+		return
+	}
+
+	var count int
+	DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	// Promote the account if it already exists
+	var user models.User
+	if err := DB.Where("username = ?", username).First(&user).Error; err == nil {
+		DB.Model(&user).Update("role", models.RoleAdmin)
+		log.Printf("Promoted user %s to admin", username)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		log.Fatal("Failed to hash admin password:", err)
+	}
+
+	// The operator configured this account, so it can order without verifying an email
+	user = models.User{
+		Username:      username,
+		Password:      hashedPassword,
+		Role:          models.RoleAdmin,
+		EmailVerified: true,
+	}
+	if err := DB.Create(&user).Error; err != nil {
+		log.Fatal("Failed to create admin user:", err)
+	}
+
+	ActiveCart(DB, user.ID)
+	log.Printf("Admin user %s created successfully", username)
 }
 
 // GetDB returns the database instance
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"errors"
+	"log"
+
+	"ecommerce-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrInsufficientStock is returned when an adjustment would take stock below zero or below what carts hold
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrItemNotFound is returned when adjusting stock of an unknown item
+	ErrItemNotFound = errors.New("item not found")
+)
+
+// StockChange describes why an item's stock is being adjusted, for the ledger
+type StockChange struct {
+	Reason  string
+	Note    string
+	OrderID *uint
+	ActorID *uint
+}
+
+// AdjustStock atomically applies delta to the item's stock, keeps its status in step with
+// the new level and records the change in the ledger. Stock never goes below the units
+// reserved by carts. Pass DB, or a transaction to make the change part of a larger unit of work.
+func AdjustStock(db *gorm.DB, itemID uint, delta int, change StockChange) (*models.StockAdjustment, error) {
+	return changeStock(db, itemID, delta, 0, change)
+}
+
+// changeStock applies delta to stock while releasing release reserved units, in one guarded statement
+func changeStock(db *gorm.DB, itemID uint, delta, release int, change StockChange) (*models.StockAdjustment, error) {
+	// The guard and the update are one statement, so concurrent orders cannot oversell
+	result := db.Model(&models.Item{}).
+		Where("id = ? AND stock - (reserved - ?) + ? >= 0", itemID, release, delta).
+		Updates(map[string]interface{}{
+			"stock":    gorm.Expr("stock + ?", delta),
+			"reserved": gorm.Expr("reserved - ?", release),
+			"status":   gorm.Expr("CASE WHEN stock + ? > 0 THEN ? ELSE ? END", delta, models.ItemStatusAvailable, models.ItemStatusOutOfStock),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int
+		db.Model(&models.Item{}).Where("id = ?", itemID).Count(&count)
+		if count == 0 {
+			return nil, ErrItemNotFound
+		}
+		return nil, ErrInsufficientStock
+	}
+
+	var item models.Item
+	if err := db.Select("stock").First(&item, itemID).Error; err != nil {
+		return nil, err
+	}
+
+	adjustment := models.StockAdjustment{
+		ItemID:     itemID,
+		Delta:      delta,
+		StockAfter: item.Stock,
+		Reason:     change.Reason,
+		Note:       change.Note,
+		OrderID:    change.OrderID,
+		ActorID:    change.ActorID,
+	}
+	if err := db.Create(&adjustment).Error; err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// bookLegacyStock gives every item created before stock was tracked an opening stock of quantity,
+// recorded in the ledger. With no opening stock the items are out of stock until restocked.
+func bookLegacyStock(quantity int) error {
+	var items []models.Item
+	if err := DB.Find(&items).Error; err != nil {
+		return err
+	}
+	for _, item := range items {
+		change := StockChange{Reason: models.StockReasonCorrection, Note: "Opening stock when stock tracking was introduced"}
+		if _, err := AdjustStock(DB, item.ID, quantity, change); err != nil {
+			return err
+		}
+	}
+	if quantity <= 0 && len(items) > 0 {
+		log.Printf("%d existing items have no stock and are out of stock; set LEGACY_ITEM_STOCK or restock them through POST /items/:id/stock", len(items))
+	} else if len(items) > 0 {
+		log.Printf("Booked an opening stock of %d for %d existing items", quantity, len(items))
+	}
+	return nil
+}
+
+// syncItemStatuses marks items without stock as out of stock and stocked items as available
+func syncItemStatuses() {
+	DB.Model(&models.Item{}).Where("stock <= 0 AND status <> ?", models.ItemStatusOutOfStock).
+		Update("status", models.ItemStatusOutOfStock)
+	DB.Model(&models.Item{}).Where("stock > 0 AND status <> ?", models.ItemStatusAvailable).
+		Update("status", models.ItemStatusAvailable)
+}
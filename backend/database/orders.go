@@ -0,0 +1,107 @@
+package database
+
+import (
+	"errors"
+	"log"
+
+	"ecommerce-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrInvalidTransition is returned when an order may not move to the requested status
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	// ErrOrderStatusChanged is returned when the order's status changed while it was being updated
+	ErrOrderStatusChanged = errors.New("order status changed concurrently")
+)
+
+// TransitionOrder moves the order to status if the transition table allows it and records the
+// change in the order's history. The status and its history entry are written together, joining
+// db if it is already a transaction. The order's Status is updated in place.
+func TransitionOrder(db *gorm.DB, order *models.Order, status string, actorID *uint, reason string) (*models.OrderStatusEvent, error) {
+	if !models.CanTransitionOrder(order.Status, status) {
+		return nil, ErrInvalidTransition
+	}
+
+	var event *models.OrderStatusEvent
+	err := Transaction(db, func(tx *gorm.DB) error {
+		// Guarding on the current status stops two concurrent changes from both applying
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", order.ID, order.Status).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderStatusChanged
+		}
+
+		var err error
+		event, err = recordOrderStatus(tx, order.ID, order.Status, status, actorID, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	order.Status = status
+	return event, nil
+}
+
+// RecordOrderPlaced starts the history of a newly created order
+func RecordOrderPlaced(db *gorm.DB, order *models.Order, actorID *uint) (*models.OrderStatusEvent, error) {
+	return recordOrderStatus(db, order.ID, "", order.Status, actorID, "")
+}
+
+// markLegacyOrders moves orders placed before statuses were tracked to the legacy status and starts
+// their history, so they aren't mistaken for orders still awaiting payment
+func markLegacyOrders() error {
+	return Transaction(DB, func(tx *gorm.DB) error {
+		var orders []models.Order
+		if err := tx.Find(&orders).Error; err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).UpdateColumn("status", models.OrderStatusLegacy).Error; err != nil {
+				return err
+			}
+			event := models.OrderStatusEvent{
+				OrderID:   order.ID,
+				ToStatus:  models.OrderStatusLegacy,
+				Reason:    "Placed before order statuses were tracked",
+				CreatedAt: order.CreatedAt,
+			}
+			if err := tx.Create(&event).Error; err != nil {
+				return err
+			}
+		}
+		if len(orders) > 0 {
+			log.Printf("Marked %d existing orders as %s", len(orders), models.OrderStatusLegacy)
+		}
+		return nil
+	})
+}
+
+// recordOrderStatus writes one entry of an order's status history
+func recordOrderStatus(db *gorm.DB, orderID uint, from, to string, actorID *uint, reason string) (*models.OrderStatusEvent, error) {
+	event := models.OrderStatusEvent{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    actorID,
+		Reason:     reason,
+	}
+	if err := db.Create(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// OrderWasPaid reports whether the order's history shows a captured payment
+func OrderWasPaid(db *gorm.DB, orderID uint) bool {
+	var count int
+	db.Model(&models.OrderStatusEvent{}).
+		Where("order_id = ? AND to_status = ?", orderID, models.OrderStatusPaid).
+		Count(&count)
+	return count > 0
+}
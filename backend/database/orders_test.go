@@ -0,0 +1,33 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"ecommerce-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+func TestTransitionOrderFailedHistoryKeepsStatus(t *testing.T) {
+	setupTestDB(t)
+	order := models.Order{CartID: 1, UserID: 1, Status: models.OrderStatusPendingPayment}
+	if err := DB.Create(&order).Error; err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	DB.Callback().Create().Before("gorm:create").Register("test:fail_status_events", func(scope *gorm.Scope) {
+		if scope.TableName() == "order_status_events" {
+			scope.Err(errors.New("injected history failure"))
+		}
+	})
+
+	if _, err := TransitionOrder(DB, &order, models.OrderStatusPaid, nil, ""); err == nil {
+		t.Fatal("transition succeeded without its history entry")
+	}
+
+	var stored models.Order
+	DB.First(&stored, order.ID)
+	if stored.Status != models.OrderStatusPendingPayment || order.Status != models.OrderStatusPendingPayment {
+		t.Errorf("status = %q (in memory %q), want %q", stored.Status, order.Status, models.OrderStatusPendingPayment)
+	}
+}
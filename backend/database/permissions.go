@@ -0,0 +1,80 @@
+package database
+
+import (
+	"log"
+
+	"ecommerce-backend/models"
+)
+
+// seedRoles creates the permission catalog and keeps the built-in roles' permissions in step with
+// DefaultRolePermissions, so permissions added in a release reach existing installs
+func seedRoles() {
+	for _, name := range models.AllPermissions {
+		if err := DB.FirstOrCreate(&models.Permission{}, models.Permission{Name: name}).Error; err != nil {
+			log.Fatal("Failed to seed permissions:", err)
+		}
+	}
+
+	for name, perms := range models.DefaultRolePermissions {
+		permissions, err := FindPermissions(perms)
+		if err != nil {
+			log.Fatal("Failed to seed roles:", err)
+		}
+
+		var role models.Role
+		if err := DB.Where("name = ?", name).First(&role).Error; err == nil {
+			if err := DB.Model(&role).Association("Permissions").Replace(permissions).Error; err != nil {
+				log.Fatal("Failed to sync roles:", err)
+			}
+			continue
+		}
+
+		role = models.Role{
+			Name:        name,
+			Builtin:     true,
+			Permissions: permissions,
+		}
+		if err := DB.Create(&role).Error; err != nil {
+			log.Fatal("Failed to seed roles:", err)
+		}
+	}
+}
+
+// FindPermissions loads the permission rows for the given names
+func FindPermissions(names []string) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	err := DB.Where("name IN (?)", names).Find(&permissions).Error
+	return permissions, err
+}
+
+// RolePermissions returns the permission names granted to the named role
+func RolePermissions(roleName string) []string {
+	names := []string{}
+	DB.Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", roleName).
+		Pluck("permissions.name", &names)
+	return names
+}
+
+// HasPermission reports whether the named role grants the permission
+func HasPermission(roleName, permission string) bool {
+	var count int
+	DB.Table("role_permissions").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("roles.name = ? AND permissions.name = ?", roleName, permission).
+		Count(&count)
+	return count > 0
+}
+
+// RoleExists reports whether a role with the given name exists
+func RoleExists(roleName string) bool {
+	var count int
+	DB.Model(&models.Role{}).Where("name = ?", roleName).Count(&count)
+	return count > 0
+}
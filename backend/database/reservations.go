@@ -0,0 +1,140 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"ecommerce-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ReserveStock sets the units of an item held for a cart to quantity and extends the hold on
+// the whole cart to expiresAt. It fails with ErrInsufficientStock if not enough unreserved stock remains.
+func ReserveStock(db *gorm.DB, cartID, itemID uint, quantity int, expiresAt time.Time) error {
+	var reservation models.StockReservation
+	found := db.Where("cart_id = ? AND item_id = ?", cartID, itemID).First(&reservation).Error == nil
+
+	if delta := quantity - reservation.Quantity; delta != 0 {
+		query := db.Model(&models.Item{}).Where("id = ?", itemID)
+		if delta > 0 {
+			query = query.Where("stock - reserved >= ?", delta)
+		}
+		result := query.Update("reserved", gorm.Expr("reserved + ?", delta))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientStock
+		}
+	}
+
+	if found {
+		if err := db.Model(&reservation).Updates(map[string]interface{}{"quantity": quantity, "expires_at": expiresAt}).Error; err != nil {
+			return err
+		}
+	} else {
+		reservation = models.StockReservation{
+			CartID:    cartID,
+			ItemID:    itemID,
+			Quantity:  quantity,
+			ExpiresAt: expiresAt,
+		}
+		if err := db.Create(&reservation).Error; err != nil {
+			return err
+		}
+	}
+
+	// Activity on a cart keeps everything in it held
+	return db.Model(&models.StockReservation{}).Where("cart_id = ?", cartID).Update("expires_at", expiresAt).Error
+}
+
+// ReleaseReservation returns the units held for one item in a cart to general stock
+func ReleaseReservation(db *gorm.DB, cartID, itemID uint) {
+	var reservations []models.StockReservation
+	db.Where("cart_id = ? AND item_id = ?", cartID, itemID).Find(&reservations)
+	releaseReservations(db, reservations)
+}
+
+// ReleaseCartReservations returns every unit held for a cart to general stock
+func ReleaseCartReservations(db *gorm.DB, cartID uint) {
+	var reservations []models.StockReservation
+	db.Where("cart_id = ?", cartID).Find(&reservations)
+	releaseReservations(db, reservations)
+}
+
+// ReleaseExpiredReservations returns units held past their expiry to general stock
+func ReleaseExpiredReservations() int {
+	var reservations []models.StockReservation
+	DB.Where("expires_at < ?", time.Now()).Find(&reservations)
+
+	released := 0
+	Transaction(DB, func(tx *gorm.DB) error {
+		released = releaseReservations(tx, reservations)
+		return nil
+	})
+	return released
+}
+
+// CommitReservedStock takes quantity units of an item out of stock for an order, converting the
+// cart's hold on the item. Without a hold, the units must come from unreserved stock.
+func CommitReservedStock(db *gorm.DB, cartID, itemID uint, quantity int, change StockChange) (*models.StockAdjustment, error) {
+	held := 0
+	var reservation models.StockReservation
+	if err := db.Where("cart_id = ? AND item_id = ?", cartID, itemID).First(&reservation).Error; err == nil && claimReservation(db, &reservation) {
+		held = reservation.Quantity
+	}
+
+	adjustment, err := changeStock(db, itemID, -quantity, held, change)
+	if err != nil && held > 0 {
+		releaseUnits(db, itemID, held)
+	}
+	return adjustment, err
+}
+
+// StartReservationSweeper releases expired reservations every interval until stop is called
+func StartReservationSweeper(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if released := ReleaseExpiredReservations(); released > 0 {
+					log.Printf("Released %d expired stock reservations", released)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// releaseReservations deletes the reservations and returns their units, reporting how many it released
+func releaseReservations(db *gorm.DB, reservations []models.StockReservation) int {
+	released := 0
+	for i := range reservations {
+		if claimReservation(db, &reservations[i]) {
+			releaseUnits(db, reservations[i].ItemID, reservations[i].Quantity)
+			released++
+		}
+	}
+	return released
+}
+
+// claimReservation deletes the reservation, reporting whether this caller removed it.
+// Only the claimant may return its units, so a hold is never released twice.
+func claimReservation(db *gorm.DB, reservation *models.StockReservation) bool {
+	result := db.Where("id = ?", reservation.ID).Delete(&models.StockReservation{})
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// releaseUnits lowers an item's reserved count, never below zero
+func releaseUnits(db *gorm.DB, itemID uint, units int) {
+	db.Model(&models.Item{}).Where("id = ?", itemID).
+		Update("reserved", gorm.Expr("CASE WHEN reserved >= ? THEN reserved - ? ELSE 0 END", units, units))
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"time"
+
+	"ecommerce-backend/models"
+)
+
+// RevokeSessions revokes the given sessions and adds their current access tokens to the revocation store
+func RevokeSessions(sessions []models.Session, reason string) error {
+	now := time.Now()
+	for _, session := range sessions {
+		if session.RevokedAt == nil {
+			if err := DB.Model(&session).Update("revoked_at", now).Error; err != nil {
+				return err
+			}
+		}
+		if session.TokenHash == "" {
+			continue
+		}
+		if IsTokenRevoked(session.TokenHash) {
+			continue
+		}
+		revoked := models.RevokedToken{
+			TokenHash: session.TokenHash,
+			UserID:    session.UserID,
+			Reason:    reason,
+			ExpiresAt: session.ExpiresAt,
+		}
+		if err := DB.Create(&revoked).Error; err != nil {
+			return err
+		}
+	}
+
+	// Entries past their expiry are rejected by signature checks anyway
+	return DB.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error
+}
+
+// IsTokenRevoked reports whether the access token with the given hash is in the revocation store
+func IsTokenRevoked(tokenHash string) bool {
+	var count int
+	DB.Model(&models.RevokedToken{}).Where("token_hash = ?", tokenHash).Count(&count)
+	return count > 0
+}
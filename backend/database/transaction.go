@@ -0,0 +1,10 @@
+package database
+
+import "github.com/jinzhu/gorm"
+
+// Transaction runs fn as a single unit of work on db. Everything fn does through tx is committed if it
+// returns nil and rolled back if it returns an error or panics. If db is already a transaction, fn
+// joins it and the outer caller decides whether it commits.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(fn)
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ecommerce-backend/config"
+	"ecommerce-backend/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// setupTestDB opens a fresh, seeded in-memory SQLite database as DB
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	config.LoadConfig()
+	config.AppConfig.Database.Type = "sqlite3"
+	config.AppConfig.Database.Name = "file:" + t.Name() + "?mode=memory&cache=shared"
+	config.AppConfig.Admin = config.AdminConfig{}
+	InitDatabase()
+	DB.LogMode(false)
+	t.Cleanup(func() { DB.Close() })
+}
+
+func itemStock(t *testing.T, itemID uint) (stock, reserved int) {
+	t.Helper()
+	var item models.Item
+	if err := DB.First(&item, itemID).Error; err != nil {
+		t.Fatalf("load item: %v", err)
+	}
+	return item.Stock, item.Reserved
+}
+
+func TestNestedTransactionRollsBackWithOuter(t *testing.T) {
+	setupTestDB(t)
+	injected := errors.New("injected failure")
+	var before int
+	DB.Model(&models.StockAdjustment{}).Count(&before)
+
+	err := Transaction(DB, func(tx *gorm.DB) error {
+		if _, err := AdjustStock(tx, 1, 5, StockChange{Reason: models.StockReasonRestock}); err != nil {
+			return err
+		}
+		if err := Transaction(tx, func(inner *gorm.DB) error {
+			_, err := AdjustStock(inner, 2, 5, StockChange{Reason: models.StockReasonRestock})
+			return err
+		}); err != nil {
+			return err
+		}
+		return injected
+	})
+
+	if err != injected {
+		t.Fatalf("err = %v, want %v", err, injected)
+	}
+	for _, id := range []uint{1, 2} {
+		if stock, _ := itemStock(t, id); stock != 25 {
+			t.Errorf("item %d stock = %d, want 25", id, stock)
+		}
+	}
+	var adjustments int
+	DB.Model(&models.StockAdjustment{}).Count(&adjustments)
+	if adjustments != before {
+		t.Errorf("%d stock adjustments recorded, want %d", adjustments, before)
+	}
+}
+
+func TestCommitReservedStockOversoldKeepsHold(t *testing.T) {
+	setupTestDB(t)
+	if err := ReserveStock(DB, 1, 1, 2, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+
+	err := Transaction(DB, func(tx *gorm.DB) error {
+		_, err := CommitReservedStock(tx, 1, 1, 30, StockChange{Reason: models.StockReasonOrder})
+		return err
+	})
+
+	if err != ErrInsufficientStock {
+		t.Fatalf("err = %v, want %v", err, ErrInsufficientStock)
+	}
+	if stock, reserved := itemStock(t, 1); stock != 25 || reserved != 2 {
+		t.Errorf("stock, reserved = %d, %d, want 25, 2", stock, reserved)
+	}
+	var held int
+	DB.Model(&models.StockReservation{}).Where("cart_id = ? AND item_id = ?", 1, 1).Count(&held)
+	if held != 1 {
+		t.Errorf("%d reservations left, want 1", held)
+	}
+}
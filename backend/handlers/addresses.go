@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+	"ecommerce-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAddresses caps the size of a user's address book
+const maxAddresses = 20
+
+// validateAddress normalizes the request fields into address, returning an error message if they are invalid
+func validateAddress(req *models.AddressRequest, address *models.Address) (string, bool) {
+	country, err := utils.NormalizeCountryCode(req.Country)
+	if err != nil {
+		return err.Error(), false
+	}
+	postalCode, err := utils.ValidatePostalCode(country, req.PostalCode)
+	if err != nil {
+		return err.Error(), false
+	}
+
+	phone := phoneSeparators.Replace(strings.TrimSpace(req.Phone))
+	if phone != "" && !phonePattern.MatchString(phone) {
+		return "phone must be in international format, e.g. +14155550123", false
+	}
+
+	address.Name = strings.TrimSpace(req.Name)
+	address.Line1 = strings.TrimSpace(req.Line1)
+	address.Line2 = strings.TrimSpace(req.Line2)
+	address.City = strings.TrimSpace(req.City)
+	address.Region = strings.TrimSpace(req.Region)
+	address.PostalCode = postalCode
+	address.Country = country
+	address.Phone = phone
+	address.DefaultShipping = req.DefaultShipping
+	address.DefaultBilling = req.DefaultBilling
+
+	if address.Name == "" || address.Line1 == "" || address.City == "" {
+		return "name, line1 and city must not be blank", false
+	}
+	return "", true
+}
+
+// clearOtherDefaults unsets the default flags the address now holds on the user's other addresses
+func clearOtherDefaults(address *models.Address) {
+	if address.DefaultShipping {
+		database.DB.Model(&models.Address{}).
+			Where("user_id = ? AND id <> ?", address.UserID, address.ID).
+			Update("default_shipping", false)
+	}
+	if address.DefaultBilling {
+		database.DB.Model(&models.Address{}).
+			Where("user_id = ? AND id <> ?", address.UserID, address.ID).
+			Update("default_billing", false)
+	}
+}
+
+// ListAddresses returns the current user's address book
+func ListAddresses(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var addresses []models.Address
+	if err := database.DB.Where("user_id = ?", user.ID).Order("id").Find(&addresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch addresses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addresses": addresses})
+}
+
+// GetAddress returns one of the current user's addresses
+func GetAddress(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var address models.Address
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&address).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"address": address})
+}
+
+// CreateAddress adds an address to the current user's address book.
+// The first address becomes the default for shipping and billing.
+func CreateAddress(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var req models.AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address := models.Address{UserID: user.ID}
+	if msg, ok := validateAddress(&req, &address); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var count int
+	database.DB.Model(&models.Address{}).Where("user_id = ?", user.ID).Count(&count)
+	if count >= maxAddresses {
+		c.JSON(http.StatusConflict, gin.H{"error": "Address book is full"})
+		return
+	}
+	if count == 0 {
+		address.DefaultShipping = true
+		address.DefaultBilling = true
+	}
+
+	if err := database.DB.Create(&address).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
+		return
+	}
+	clearOtherDefaults(&address)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Address created successfully",
+		"address": address,
+	})
+}
+
+// UpdateAddress replaces one of the current user's addresses
+func UpdateAddress(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var address models.Address
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&address).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	var req models.AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg, ok := validateAddress(&req, &address); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := database.DB.Save(&address).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update address"})
+		return
+	}
+	clearOtherDefaults(&address)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Address updated successfully",
+		"address": address,
+	})
+}
+
+// DeleteAddress removes one of the current user's addresses
+func DeleteAddress(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).Delete(&models.Address{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete address"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted successfully"})
+}
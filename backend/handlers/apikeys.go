@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+	"ecommerce-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateAPIKeyScopes checks that every scope exists and that permission scopes are granted to both the
+// owner's role and the creator's, so nobody can mint a key with more access than they have themselves.
+// It returns the error status and message, or zero if the scopes are valid.
+func validateAPIKeyScopes(owner, creator *models.User, scopes []string) (int, string) {
+	if len(scopes) == 0 {
+		return http.StatusBadRequest, "At least one scope is required"
+	}
+
+	for _, scope := range scopes {
+		known := false
+		for _, s := range models.APIKeyScopes {
+			if s == scope {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return http.StatusBadRequest, "Unknown scope: " + scope
+		}
+
+		for _, perm := range models.AllPermissions {
+			if perm != scope {
+				continue
+			}
+			if !database.HasPermission(creator.Role, perm) {
+				return http.StatusForbidden, "Scope not granted to your role: " + scope
+			}
+			if !database.HasPermission(owner.Role, perm) {
+				return http.StatusBadRequest, "Scope not granted to the user's role: " + scope
+			}
+		}
+	}
+
+	return 0, ""
+}
+
+// createAPIKey issues a key for owner and returns the full key, which is shown only once
+func createAPIKey(c *gin.Context, owner, creator *models.User) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, msg := validateAPIKeyScopes(owner, creator, req.Scopes); status != 0 {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	prefix, secret, err := utils.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	key := models.APIKey{
+		UserID:     owner.ID,
+		CreatedBy:  creator.ID,
+		Name:       strings.TrimSpace(req.Name),
+		Prefix:     prefix,
+		SecretHash: utils.HashToken(secret),
+		Scopes:     strings.Join(req.Scopes, " "),
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err := database.DB.Create(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created; store it now, it will not be shown again",
+		"key":     prefix + "." + secret,
+		"api_key": key,
+	})
+}
+
+// revokeAPIKey marks one of the user's keys as revoked
+func revokeAPIKey(c *gin.Context, userID uint, keyID string) {
+	result := database.DB.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+// listAPIKeys returns all of the user's keys, newest first
+func listAPIKeys(c *gin.Context, userID uint) {
+	var keys []models.APIKey
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys, "available_scopes": models.APIKeyScopes})
+}
+
+// ListAPIKeys returns the current user's API keys
+func ListAPIKeys(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	listAPIKeys(c, user.ID)
+}
+
+// CreateAPIKey issues an API key for the current user
+func CreateAPIKey(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	createAPIKey(c, user, user)
+}
+
+// RevokeAPIKey revokes one of the current user's API keys
+func RevokeAPIKey(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	revokeAPIKey(c, user.ID, c.Param("id"))
+}
+
+// ListUserAPIKeys returns another user's API keys
+func ListUserAPIKeys(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	listAPIKeys(c, user.ID)
+}
+
+// CreateUserAPIKey issues an API key on behalf of another user, such as a service account
+func CreateUserAPIKey(c *gin.Context) {
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	createAPIKey(c, &user, admin)
+}
+
+// RevokeUserAPIKey revokes one of another user's API keys
+func RevokeUserAPIKey(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	revokeAPIKey(c, user.ID, c.Param("keyId"))
+}
@@ -1,16 +1,58 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
+	"ecommerce-backend/config"
 	"ecommerce-backend/database"
 	"ecommerce-backend/middleware"
 	"ecommerce-backend/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
-// AddToCart handles adding items to a user's cart
+// maxCartQuantity caps the quantity of any item in a cart
+const maxCartQuantity = 99
+
+// quantityLimit returns the largest quantity of item a cart may hold
+func quantityLimit(item *models.Item) int {
+	if item.MaxPerOrder > 0 && item.MaxPerOrder < maxCartQuantity {
+		return item.MaxPerOrder
+	}
+	return maxCartQuantity
+}
+
+// quantityLimitError rejects a quantity above the item's purchase limit
+func quantityLimitError(item *models.Item) *apiError {
+	limit := quantityLimit(item)
+	return &apiError{http.StatusBadRequest, gin.H{
+		"error":        fmt.Sprintf("You can buy at most %d of %s", limit, item.Name),
+		"item_id":      item.ID,
+		"max_quantity": limit,
+	}}
+}
+
+// reserveCartItem holds quantity units of item for the cart, failing with a 409 if they are not available
+func reserveCartItem(tx *gorm.DB, cartID uint, item *models.Item, quantity int) error {
+	expiresAt := time.Now().Add(config.AppConfig.Store.ReservationTTLDuration())
+	err := database.ReserveStock(tx, cartID, item.ID, quantity, expiresAt)
+	if err == database.ErrInsufficientStock {
+		var current models.Item
+		tx.First(&current, item.ID)
+		return &apiError{http.StatusConflict, gin.H{
+			"error":     "Not enough stock available for " + item.Name,
+			"item_id":   item.ID,
+			"available": current.Stock - current.Reserved,
+		}}
+	}
+	return err
+}
+
+// AddToCart handles adding items to a user's cart; adding an item already in the cart increases its quantity.
+// The items are added together, so one bad item leaves the cart unchanged.
 func AddToCart(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
@@ -24,67 +66,103 @@ func AddToCart(c *gin.Context) {
 		return
 	}
 
-	// Get or create user's cart
 	var cart models.Cart
-	if err := database.DB.Where("user_id = ?", user.ID).First(&cart).Error; err != nil {
-		// Create new cart if it doesn't exist
-		cart = models.Cart{
-			UserID: user.ID,
-			Name:   "My Cart",
-			Status: "active",
+	err := database.Transaction(database.DB, func(tx *gorm.DB) error {
+		// Get or create user's active cart
+		var err error
+		if cart, err = database.ActiveCart(tx, user.ID); err != nil {
+			return err
 		}
-		if err := database.DB.Create(&cart).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cart"})
-			return
+
+		// Add items to cart
+		for _, itemID := range req.ItemIDs {
+			if err := addCartItem(tx, cart.ID, itemID); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to add item to cart")
+		return
 	}
 
-	// Add items to cart
-	for _, itemID := range req.ItemIDs {
-		// Check if item exists
-		var item models.Item
-		if err := database.DB.First(&item, itemID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Item not found"})
-			return
-		}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Items added to cart successfully",
+		"cart_id": cart.ID,
+	})
+}
 
-		// Check if item is already in cart
-		var existingCartItem models.CartItem
-		if err := database.DB.Where("cart_id = ? AND item_id = ?", cart.ID, itemID).First(&existingCartItem).Error; err == nil {
-			// Item already in cart, skip
-			continue
-		}
+// addCartItem adds one unit of an item to the cart and holds it while it stays there
+func addCartItem(tx *gorm.DB, cartID, itemID uint) error {
+	// Check if item exists
+	var item models.Item
+	if err := tx.First(&item, itemID).Error; err != nil {
+		return &apiError{http.StatusBadRequest, gin.H{"error": "Item not found", "item_id": itemID}}
+	}
+
+	if item.Status == models.ItemStatusOutOfStock {
+		return &apiError{http.StatusConflict, gin.H{"error": item.Name + " is out of stock", "item_id": item.ID}}
+	}
+
+	// A cart is totalled in a single currency
+	if msg, ok := checkCartCurrency(tx, cartID, &item); !ok {
+		return &apiError{http.StatusBadRequest, gin.H{"error": msg}}
+	}
 
-		// Add item to cart
-		cartItem := models.CartItem{
-			CartID: cart.ID,
-			ItemID: itemID,
+	// Check if item is already in cart
+	var existingCartItem models.CartItem
+	if err := tx.Where("cart_id = ? AND item_id = ?", cartID, itemID).First(&existingCartItem).Error; err == nil {
+		// Item already in cart, add one more
+		if existingCartItem.Quantity+1 > quantityLimit(&item) {
+			return quantityLimitError(&item)
 		}
-		if err := database.DB.Create(&cartItem).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to cart"})
-			return
+		if err := reserveCartItem(tx, cartID, &item, existingCartItem.Quantity+1); err != nil {
+			return err
 		}
+		return tx.Model(&models.CartItem{}).
+			Where("cart_id = ? AND item_id = ?", cartID, itemID).
+			Update("quantity", existingCartItem.Quantity+1).Error
 	}
 
-	// Update user's cart ID
-	user.CartID = &cart.ID
-	database.DB.Save(&user)
+	if err := reserveCartItem(tx, cartID, &item, 1); err != nil {
+		return err
+	}
+	cartItem := models.CartItem{
+		CartID:   cartID,
+		ItemID:   itemID,
+		Quantity: 1,
+	}
+	return tx.Create(&cartItem).Error
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Items added to cart successfully",
-		"cart_id": cart.ID,
-	})
+// checkCartCurrency rejects an item priced in a different currency from the items already in the cart
+func checkCartCurrency(db *gorm.DB, cartID uint, item *models.Item) (string, bool) {
+	var existing models.CartItem
+	if err := db.Where("cart_id = ? AND item_id <> ?", cartID, item.ID).Preload("Item").First(&existing).Error; err != nil || existing.Item == nil {
+		return "", true
+	}
+	if existing.Item.Price.Currency != item.Price.Currency {
+		return "Item is priced in " + item.Price.Currency + " but the cart is in " + existing.Item.Price.Currency, false
+	}
+	return "", true
 }
 
 // ListCarts returns all carts
 func ListCarts(c *gin.Context) {
 	var carts []models.Cart
-	if err := database.DB.Preload("User").Preload("Items").Find(&carts).Error; err != nil {
+	if err := database.DB.Preload("User").Preload("CartItems.Item").Find(&carts).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch carts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"carts": carts})
+	views, err := models.NewCartViews(carts, config.AppConfig.Store.Currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to total carts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"carts": views})
 }
 
 // GetUserCart returns the current user's cart
@@ -95,9 +173,9 @@ func GetUserCart(c *gin.Context) {
 		return
 	}
 
-	var cart models.Cart
-	if err := database.DB.Where("user_id = ?", user.ID).First(&cart).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+	cart, err := database.ActiveCart(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
 		return
 	}
 
@@ -108,17 +186,91 @@ func GetUserCart(c *gin.Context) {
 		return
 	}
 
-	// Extract items from cart items
-	var items []models.Item
-	for _, cartItem := range cartItems {
-		if cartItem.Item != nil {
-			items = append(items, *cartItem.Item)
+	// Price each line and total the cart in integer minor units
+	cart.CartItems = cartItems
+	database.DB.Where("cart_id = ?", cart.ID).Find(&cart.Reservations)
+	view, err := models.NewCartView(&cart, config.AppConfig.Store.Currency)
+	if err == models.ErrCurrencyMismatch {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cart contains items priced in different currencies"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to total cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": view})
+}
+
+// UpdateCartItem sets the quantity of an item in the user's cart; a quantity of zero removes it
+func UpdateCartItem(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var req models.UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := database.ActiveCart(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return
+	}
+
+	var cartItem models.CartItem
+	if err := database.DB.Where("cart_id = ? AND item_id = ?", cart.ID, c.Param("id")).Preload("Item").First(&cartItem).Error; err != nil || cartItem.Item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not in cart"})
+		return
+	}
+
+	if *req.Quantity == 0 {
+		if err := database.Transaction(database.DB, func(tx *gorm.DB) error {
+			return removeCartItem(tx, cart.ID, cartItem.ItemID)
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item from cart"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Item removed from cart successfully"})
+		return
+	}
+
+	if *req.Quantity > quantityLimit(cartItem.Item) {
+		limitErr := quantityLimitError(cartItem.Item)
+		c.JSON(limitErr.status, limitErr.body)
+		return
+	}
+	err = database.Transaction(database.DB, func(tx *gorm.DB) error {
+		if err := reserveCartItem(tx, cart.ID, cartItem.Item, *req.Quantity); err != nil {
+			return err
 		}
+		return tx.Model(&models.CartItem{}).
+			Where("cart_id = ? AND item_id = ?", cart.ID, cartItem.ItemID).
+			Update("quantity", *req.Quantity).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update cart item")
+		return
 	}
 
-	cart.Items = items
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Cart item updated successfully",
+		"item_id":  cartItem.ItemID,
+		"quantity": *req.Quantity,
+	})
+}
 
-	c.JSON(http.StatusOK, gin.H{"cart": cart})
+// removeCartItem deletes an item from the cart and releases the units held for it
+func removeCartItem(tx *gorm.DB, cartID, itemID uint) error {
+	if err := tx.Where("cart_id = ? AND item_id = ?", cartID, itemID).Delete(&models.CartItem{}).Error; err != nil {
+		return err
+	}
+	database.ReleaseReservation(tx, cartID, itemID)
+	return nil
 }
 
 // RemoveFromCart removes a specific item from the user's cart
@@ -137,14 +289,16 @@ func RemoveFromCart(c *gin.Context) {
 		return
 	}
 
-	var cart models.Cart
-	if err := database.DB.Where("user_id = ?", user.ID).First(&cart).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+	cart, err := database.ActiveCart(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
 		return
 	}
 
 	// Delete the specific cart item
-	if err := database.DB.Where("cart_id = ? AND item_id = ?", cart.ID, req.ItemID).Delete(&models.CartItem{}).Error; err != nil {
+	if err := database.Transaction(database.DB, func(tx *gorm.DB) error {
+		return removeCartItem(tx, cart.ID, req.ItemID)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item from cart"})
 		return
 	}
@@ -160,14 +314,20 @@ func ClearCart(c *gin.Context) {
 		return
 	}
 
-	var cart models.Cart
-	if err := database.DB.Where("user_id = ?", user.ID).First(&cart).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+	cart, err := database.ActiveCart(database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
 		return
 	}
 
-	// Delete all cart items for this cart
-	if err := database.DB.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
+	// Delete all cart items for this cart along with their reservations
+	if err := database.Transaction(database.DB, func(tx *gorm.DB) error {
+		if err := tx.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		database.ReleaseCartReservations(tx, cart.ID)
+		return nil
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
 		return
 	}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ecommerce-backend/config"
+	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+	"ecommerce-backend/notify"
+	"ecommerce-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// normalizeEmail lowercases and trims an email address for storage and comparison
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// notifyUser sends a message to the user's email, or to their username if they have none
+func notifyUser(user *models.User, subject, body string) {
+	to := user.Email
+	if to == "" {
+		to = user.Username
+	}
+	if err := notify.Send(notify.Message{To: to, Subject: subject, Body: body}); err != nil {
+		log.Printf("Failed to send %q to user %d: %v", subject, user.ID, err)
+	}
+}
+
+// sendEmailVerification invalidates outstanding verification tokens and mails a new one
+func sendEmailVerification(user *models.User) error {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	database.DB.Model(&models.EmailVerificationToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", now)
+
+	verification := models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(config.AppConfig.Auth.EmailVerifyTTLDuration()),
+	}
+	if err := database.DB.Create(&verification).Error; err != nil {
+		return err
+	}
+
+	notifyUser(user, "Verify your email address", fmt.Sprintf(
+		"Use this token to verify your email address: %s\nIt expires at %s.",
+		token, verification.ExpiresAt.Format(time.RFC1123)))
+	return nil
+}
+
+// VerifyEmail marks the user's email as verified using a verification token
+func VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var verification models.EmailVerificationToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(req.Token)).First(&verification).Error; err != nil ||
+		verification.UsedAt != nil || time.Now().After(verification.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, verification.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+
+	// The token only proves ownership of the address it was sent to
+	if user.Email != verification.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email address has changed since this token was sent"})
+		return
+	}
+
+	now := time.Now()
+	result := database.DB.Model(&models.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", verification.ID).
+		Update("used_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Updates(map[string]interface{}{
+		"email_verified":    true,
+		"email_verified_at": now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification token, at most once per EMAIL_RESEND_INTERVAL
+func ResendVerification(c *gin.Context) {
+	contextUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, contextUser.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No email address on file"})
+		return
+	}
+	if user.EmailVerified {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	var last models.EmailVerificationToken
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at desc").First(&last).Error; err == nil {
+		next := last.CreatedAt.Add(config.AppConfig.Auth.EmailResendIntervalDuration())
+		if time.Now().Before(next) {
+			respondTooManyAttempts(c, time.Until(next), "Verification email was sent recently")
+			return
+		}
+	}
+
+	if err := sendEmailVerification(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
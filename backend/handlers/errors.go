@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiError is an error response decided inside a transaction. Returning it rolls the
+// transaction back; the handler then sends it with respondError.
+type apiError struct {
+	status int
+	body   gin.H
+}
+
+func (e *apiError) Error() string {
+	if msg, ok := e.body["error"].(string); ok {
+		return msg
+	}
+	return http.StatusText(e.status)
+}
+
+// respondError sends an apiError as-is and any other error as a 500 with the given message
+func respondError(c *gin.Context, err error, message string) {
+	if apiErr, ok := err.(*apiError); ok {
+		c.JSON(apiErr.status, apiErr.body)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
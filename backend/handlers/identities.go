@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"ecommerce-backend/database"
+	"ecommerce-backend/identity"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+	"ecommerce-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oauthStateTTL bounds how long a user may take at the identity provider
+const oauthStateTTL = 10 * time.Minute
+
+var usernameCleanup = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// startAuthorization records a PKCE/nonce state and returns the provider's authorization URL.
+// A non-nil linkUserID links the identity to that user instead of logging in.
+func startAuthorization(provider identity.Provider, linkUserID *uint) (string, error) {
+	state, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, challenge, err := identity.NewPKCE()
+	if err != nil {
+		return "", err
+	}
+
+	record := models.OAuthState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Name(),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(identity.AuthRequest{
+		State:         state,
+		Nonce:         nonce,
+		CodeChallenge: challenge,
+	}), nil
+}
+
+// claimOAuthState marks an unexpired state of the provider as used and returns it. A nil linkUserID
+// only matches login states; otherwise the state must have been started by that user to link.
+func claimOAuthState(provider identity.Provider, stateToken string, linkUserID *uint) (*models.OAuthState, bool) {
+	query := database.DB.Where("state_hash = ? AND provider = ?", utils.HashToken(stateToken), provider.Name())
+	if linkUserID == nil {
+		query = query.Where("link_user_id IS NULL")
+	} else {
+		query = query.Where("link_user_id = ?", *linkUserID)
+	}
+
+	var state models.OAuthState
+	if err := query.First(&state).Error; err != nil || state.UsedAt != nil || time.Now().After(state.ExpiresAt) {
+		return nil, false
+	}
+	result := database.DB.Model(&models.OAuthState{}).
+		Where("id = ? AND used_at IS NULL", state.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, false
+	}
+	return &state, true
+}
+
+// ListProviders returns the names of the configured identity providers
+func ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": identity.Names()})
+}
+
+// ProviderLogin redirects the browser to the identity provider to sign in
+func ProviderLogin(c *gin.Context) {
+	provider, err := identity.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+		return
+	}
+
+	authURL, err := startAuthorization(provider, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// ProviderCallback completes the authorization-code flow and logs in. For a linking flow it hands
+// the code back to be finished by CompleteLinkIdentity, since this request is not authenticated.
+func ProviderCallback(c *gin.Context) {
+	provider, err := identity.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider returned an error", "code": errCode})
+		return
+	}
+	code, stateToken := c.Query("code"), c.Query("state")
+	if code == "" || stateToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+		return
+	}
+
+	var pending models.OAuthState
+	if err := database.DB.Where("state_hash = ? AND provider = ?", utils.HashToken(stateToken), provider.Name()).First(&pending).Error; err == nil && pending.LinkUserID != nil {
+		// The browser arriving here may not belong to the user who started linking, so the
+		// signed-in user must hand the code back to finish it
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Finish linking from your signed-in account with POST /users/me/identities/" + provider.Name() + "/complete",
+			"code":    code,
+			"state":   stateToken,
+		})
+		return
+	}
+
+	state, ok := claimOAuthState(provider, stateToken, nil)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired state"})
+		return
+	}
+
+	external, err := provider.Exchange(c.Request.Context(), code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("Identity provider %s exchange failed: %v", provider.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to verify identity"})
+		return
+	}
+
+	var linked models.LinkedIdentity
+	linkErr := database.DB.Where("provider = ? AND subject = ?", external.Provider, external.Subject).First(&linked).Error
+
+	// Logging in: use the linked account, or link/provision one
+	var user models.User
+	if linkErr == nil {
+		if err := database.DB.First(&user, linked.UserID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			return
+		}
+		now := time.Now()
+		database.DB.Model(&linked).Update("last_login_at", now)
+	} else {
+		provisioned, status, msg := provisionExternalUser(external)
+		if provisioned == nil {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+		user = *provisioned
+	}
+
+	recordLoginAttempt(c, &user, user.Username, true, "identity_provider:"+provider.Name())
+	beginLogin(c, &user, "")
+}
+
+// provisionExternalUser finds or creates the local user for a first-time external login.
+// An existing account is only linked automatically when both sides have verified the email.
+func provisionExternalUser(external *identity.ExternalIdentity) (*models.User, int, string) {
+	email := normalizeEmail(external.Email)
+
+	if email != "" {
+		var existing models.User
+		if err := database.DB.Where("email = ?", email).First(&existing).Error; err == nil {
+			if !external.EmailVerified || !existing.EmailVerified {
+				return nil, http.StatusConflict, "An account with this email already exists; sign in and link the provider from your profile"
+			}
+			if _, err := linkIdentity(existing.ID, external); err != nil {
+				return nil, http.StatusInternalServerError, "Failed to link identity"
+			}
+			return &existing, 0, ""
+		}
+	}
+
+	// External users sign in through the provider; the random password is never revealed
+	randomPassword, err := utils.GenerateToken()
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to create user"
+	}
+	hashedPassword, err := utils.HashPassword(randomPassword)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to create user"
+	}
+
+	user := models.User{
+		Username: uniqueUsername(external),
+		Password: hashedPassword,
+		Role:     models.RoleCustomer,
+		Email:    email,
+	}
+	if email != "" && external.EmailVerified {
+		now := time.Now()
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		return nil, http.StatusInternalServerError, "Failed to create user"
+	}
+
+	cart, err := database.ActiveCart(database.DB, user.ID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to create cart"
+	}
+	user.CartID = &cart.ID
+
+	if _, err := linkIdentity(user.ID, external); err != nil {
+		return nil, http.StatusInternalServerError, "Failed to link identity"
+	}
+	if email != "" && !user.EmailVerified {
+		if err := sendEmailVerification(&user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
+	return &user, 0, ""
+}
+
+// linkIdentity stores a LinkedIdentity row for the user
+func linkIdentity(userID uint, external *identity.ExternalIdentity) (*models.LinkedIdentity, error) {
+	now := time.Now()
+	linked := models.LinkedIdentity{
+		UserID:      userID,
+		Provider:    external.Provider,
+		Subject:     external.Subject,
+		Email:       normalizeEmail(external.Email),
+		LastLoginAt: &now,
+	}
+	if err := database.DB.Create(&linked).Error; err != nil {
+		return nil, err
+	}
+	return &linked, nil
+}
+
+// uniqueUsername derives an unused username from the external identity
+func uniqueUsername(external *identity.ExternalIdentity) string {
+	base := ""
+	if at := strings.Index(external.Email, "@"); at > 0 {
+		base = external.Email[:at]
+	} else if external.Name != "" {
+		base = external.Name
+	}
+	base = strings.Trim(usernameCleanup.ReplaceAllString(base, ""), ".-")
+	if base == "" {
+		base = external.Provider + "_user"
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		var count int
+		database.DB.Model(&models.User{}).Where("username = ?", candidate).Count(&count)
+		if count == 0 {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// ListIdentities returns the external identities linked to the current user
+func ListIdentities(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var identities []models.LinkedIdentity
+	if err := database.DB.Where("user_id = ?", user.ID).Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// LinkIdentity returns the authorization URL that links a provider account to the current user
+func LinkIdentity(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	provider, err := identity.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+		return
+	}
+
+	userID := user.ID
+	authURL, err := startAuthorization(provider, &userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start linking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// CompleteLinkIdentity finishes linking with the code and state the provider returned to the callback.
+// Only the user who started linking can complete it.
+func CompleteLinkIdentity(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	provider, err := identity.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity provider not found"})
+		return
+	}
+
+	var req models.CompleteLinkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := user.ID
+	state, ok := claimOAuthState(provider, req.State, &userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired state"})
+		return
+	}
+
+	external, err := provider.Exchange(c.Request.Context(), req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("Identity provider %s exchange failed: %v", provider.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to verify identity"})
+		return
+	}
+
+	var linked models.LinkedIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", external.Provider, external.Subject).First(&linked).Error; err == nil {
+		if linked.UserID == user.ID {
+			c.JSON(http.StatusOK, gin.H{"message": "Identity already linked", "identity": linked})
+		} else {
+			c.JSON(http.StatusConflict, gin.H{"error": "Identity is linked to another account"})
+		}
+		return
+	}
+
+	identityRow, err := linkIdentity(user.ID, external)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Identity linked successfully", "identity": identityRow})
+}
+
+// UnlinkIdentity removes one of the current user's linked identities
+func UnlinkIdentity(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).Delete(&models.LinkedIdentity{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdjustItemStock applies a manual stock adjustment, such as a restock or a stock-take correction
+func AdjustItemStock(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	var req models.AdjustStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	validReason := false
+	for _, reason := range models.ManualStockReasons {
+		if req.Reason == reason {
+			validReason = true
+			break
+		}
+	}
+	if !validReason {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reason", "allowed_reasons": models.ManualStockReasons})
+		return
+	}
+
+	adjustment, err := database.AdjustStock(database.DB, uint(itemID), req.Delta, database.StockChange{
+		Reason:  req.Reason,
+		Note:    req.Note,
+		ActorID: &user.ID,
+	})
+	switch err {
+	case nil:
+	case database.ErrItemNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	case database.ErrInsufficientStock:
+		c.JSON(http.StatusConflict, gin.H{"error": "Adjustment would take stock below zero"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust stock"})
+		return
+	}
+
+	var item models.Item
+	database.DB.First(&item, itemID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Stock adjusted successfully",
+		"item":       models.NewItemView(&item),
+		"adjustment": adjustment,
+	})
+}
+
+// ListStockAdjustments returns an item's current stock and its most recent ledger entries
+func ListStockAdjustments(c *gin.Context) {
+	var item models.Item
+	if err := database.DB.First(&item, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	var adjustments []models.StockAdjustment
+	if err := database.DB.Where("item_id = ?", item.ID).Order("id desc").Limit(200).Find(&adjustments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock adjustments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"item_id":     item.ID,
+		"stock":       item.Stock,
+		"status":      item.Status,
+		"adjustments": adjustments,
+	})
+}
@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
+	"ecommerce-backend/config"
 	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
 	"ecommerce-backend/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 // CreateItem handles item creation
@@ -17,24 +21,54 @@ func CreateItem(c *gin.Context) {
 		return
 	}
 
-	// Set default status if not provided
-	if req.Status == "" {
-		req.Status = "available"
+	// Prices are integer minor units in an ISO 4217 currency
+	price := *req.Price
+	price.Currency = strings.ToUpper(strings.TrimSpace(price.Currency))
+	if price.Currency == "" {
+		price.Currency = config.AppConfig.Store.Currency
+	}
+	if !models.ValidCurrency(price.Currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price.currency must be an ISO 4217 code, e.g. USD"})
+		return
+	}
+	if price.Amount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price.amount must not be negative"})
+		return
 	}
 
+	// Items start empty; the opening stock is booked through the ledger
 	item := models.Item{
-		Name:   req.Name,
-		Status: req.Status,
+		Name:        req.Name,
+		Status:      models.ItemStatusOutOfStock,
+		Price:       price,
+		MaxPerOrder: req.MaxPerOrder,
 	}
 
-	if err := database.DB.Create(&item).Error; err != nil {
+	// The item and its opening stock are created together or not at all
+	err := database.Transaction(database.DB, func(tx *gorm.DB) error {
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		if req.Stock <= 0 {
+			return nil
+		}
+		change := database.StockChange{Reason: models.StockReasonRestock, Note: "Opening stock"}
+		if user, exists := middleware.GetUserFromContext(c); exists {
+			change.ActorID = &user.ID
+		}
+		if _, err := database.AdjustStock(tx, item.ID, req.Stock, change); err != nil {
+			return err
+		}
+		return tx.First(&item, item.ID).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create item"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Item created successfully",
-		"item":    item,
+		"item":    models.NewItemView(&item),
 	})
 }
 
@@ -46,5 +80,5 @@ func ListItems(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"items": items})
+	c.JSON(http.StatusOK, gin.H{"items": models.NewItemViews(items)})
 } 
\ No newline at end of file
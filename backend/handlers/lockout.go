@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ecommerce-backend/config"
+	"ecommerce-backend/database"
+	"ecommerce-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// recordLoginAttempt stores a login attempt for auditing and IP throttling
+func recordLoginAttempt(c *gin.Context, user *models.User, username string, success bool, reason string) {
+	attempt := models.LoginAttempt{
+		Username:  username,
+		IPAddress: c.ClientIP(),
+		Success:   success,
+		Reason:    reason,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+	database.DB.Create(&attempt)
+}
+
+// ipRetryAfter returns how long the IP must wait before trying again, or zero if it is not throttled
+func ipRetryAfter(ip string) time.Duration {
+	limit := config.AppConfig.Auth.IPFailureLimit
+	if limit <= 0 {
+		return 0
+	}
+	window := config.AppConfig.Auth.IPFailureWindowDuration()
+
+	var failures []models.LoginAttempt
+	database.DB.Where("ip_address = ? AND success = ? AND created_at > ?", ip, false, time.Now().Add(-window)).
+		Order("created_at asc").Find(&failures)
+	if len(failures) < limit {
+		return 0
+	}
+
+	// The IP is allowed again once enough failures have aged out of the window
+	oldest := failures[len(failures)-limit]
+	return time.Until(oldest.CreatedAt.Add(window))
+}
+
+// lockoutDuration returns the lock length after the given number of consecutive failures,
+// doubling for each failure past the threshold
+func lockoutDuration(failures int) time.Duration {
+	auth := config.AppConfig.Auth
+	if auth.LockoutThreshold <= 0 || failures < auth.LockoutThreshold {
+		return 0
+	}
+
+	d := auth.LockoutBase()
+	for i := auth.LockoutThreshold; i < failures && d < auth.LockoutMax(); i++ {
+		d *= 2
+	}
+	if d > auth.LockoutMax() {
+		d = auth.LockoutMax()
+	}
+	return d
+}
+
+// registerFailedLogin increments the user's failure count and locks the account once the threshold is reached
+func registerFailedLogin(user *models.User) {
+	// Increment in SQL so concurrent failures each count, then lock based on the stored total
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		Update("failed_logins", gorm.Expr("failed_logins + 1")).Error; err != nil {
+		return
+	}
+	var current models.User
+	if err := database.DB.Select("failed_logins").First(&current, user.ID).Error; err != nil {
+		return
+	}
+	user.FailedLogins = current.FailedLogins
+
+	if d := lockoutDuration(current.FailedLogins); d > 0 {
+		lockedUntil := time.Now().Add(d)
+		user.LockedUntil = &lockedUntil
+		database.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("locked_until", lockedUntil)
+	}
+}
+
+// clearFailedLogins resets the user's brute-force state after a successful login
+func clearFailedLogins(user *models.User) {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	database.DB.Model(user).Updates(map[string]interface{}{"failed_logins": 0, "locked_until": nil})
+}
+
+// respondTooManyAttempts rejects the request with 429 and a Retry-After header
+func respondTooManyAttempts(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"retry_after": seconds,
+	})
+}
+
+// ListLoginAttempts returns the most recent login attempts for a user
+func ListLoginAttempts(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var attempts []models.LoginAttempt
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at desc").Limit(100).Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login attempts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"failed_logins": user.FailedLogins,
+		"locked_until":  user.LockedUntil,
+		"attempts":      attempts,
+	})
+}
+
+// UnlockUser clears a user's lockout and failed login count
+func UnlockUser(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Updates(map[string]interface{}{"failed_logins": 0, "locked_until": nil}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
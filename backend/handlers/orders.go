@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"ecommerce-backend/database"
 	"ecommerce-backend/middleware"
 	"ecommerce-backend/models"
+	"ecommerce-backend/payment"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 // CreateOrder handles converting a cart to an order
@@ -24,6 +27,17 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
+	// Only users with a verified email may place orders
+	var account models.User
+	if err := database.DB.First(&account, user.ID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+	if !account.EmailVerified {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email address before placing orders", "code": "email_unverified"})
+		return
+	}
+
 	// Verify the cart belongs to the user
 	var cart models.Cart
 	if err := database.DB.Where("id = ? AND user_id = ?", req.CartID, user.ID).First(&cart).Error; err != nil {
@@ -31,60 +45,148 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// Check if cart is active
-	if cart.Status != "active" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is not active"})
-		return
-	}
+	var order models.Order
+	var next models.Cart
+	err := database.Transaction(database.DB, func(tx *gorm.DB) error {
+		// Claiming the cart first means a second checkout of the same cart finds it inactive
+		claim := tx.Model(&models.Cart{}).
+			Where("id = ? AND status = ?", cart.ID, models.CartStatusActive).
+			Update("status", models.CartStatusConverted)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return &apiError{http.StatusBadRequest, gin.H{"error": "Cart is not active"}}
+		}
 
-	// Check if cart has items
-	var cartItems []models.CartItem
-	if err := database.DB.Where("cart_id = ?", cart.ID).Find(&cartItems).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart items"})
-		return
-	}
+		// Check if cart has items
+		var cartItems []models.CartItem
+		if err := tx.Where("cart_id = ?", cart.ID).Preload("Item").Find(&cartItems).Error; err != nil {
+			return err
+		}
+		if len(cartItems) == 0 {
+			return &apiError{http.StatusBadRequest, gin.H{"error": "Cart is empty"}}
+		}
 
-	if len(cartItems) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
-		return
-	}
+		// Snapshot what is being bought so the order is unaffected by later catalog changes
+		lines, total, err := buildOrderLines(cartItems)
+		if err == models.ErrCurrencyMismatch {
+			return &apiError{http.StatusConflict, gin.H{"error": "Cart contains items priced in different currencies"}}
+		}
+		if err != nil {
+			return err
+		}
 
-	// Create order
-	order := models.Order{
-		CartID: cart.ID,
-		UserID: user.ID,
-	}
+		// Create order
+		order = models.Order{
+			CartID: cart.ID,
+			UserID: user.ID,
+			Total:  total,
+			Status: models.OrderStatusPendingPayment,
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		placed, err := database.RecordOrderPlaced(tx, &order, &user.ID)
+		if err != nil {
+			return err
+		}
+		order.StatusEvents = []models.OrderStatusEvent{*placed}
+		for i := range lines {
+			lines[i].OrderID = order.ID
+			if err := tx.Create(&lines[i]).Error; err != nil {
+				return err
+			}
+		}
+		order.Lines = lines
 
-	if err := database.DB.Create(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
-		return
-	}
+		// Take the ordered quantities out of stock; an oversold line cancels the whole order
+		if err := commitOrderStock(tx, &order, cartItems); err != nil {
+			return err
+		}
 
-	// Remove all items from the cart
-	if err := database.DB.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart items"})
+		// Remove all items from the converted cart and open a fresh one for the user's next order
+		if err := tx.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		next, err = database.ActiveCart(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create order")
 		return
 	}
 
-	// Update cart status to "converted"
-	cart.Status = "converted"
-	database.DB.Save(&cart)
-
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Order created successfully",
-		"order":   order,
+		"order":   models.NewOrderView(&order),
+		"cart_id": next.ID,
 	})
 }
 
+// buildOrderLines prices each cart line from its preloaded Item and totals them in the currency of the first line
+func buildOrderLines(cartItems []models.CartItem) ([]models.OrderLine, models.Money, error) {
+	var total models.Money
+	lines := make([]models.OrderLine, 0, len(cartItems))
+	for _, cartItem := range cartItems {
+		if cartItem.Item == nil {
+			continue
+		}
+		lineTotal, err := cartItem.Item.Price.Multiply(int64(cartItem.Quantity))
+		if err != nil {
+			return nil, models.Money{}, err
+		}
+		if len(lines) == 0 {
+			total.Currency = lineTotal.Currency
+		}
+		if total, err = total.Add(lineTotal); err != nil {
+			return nil, models.Money{}, err
+		}
+		lines = append(lines, models.OrderLine{
+			ItemID:    cartItem.ItemID,
+			Name:      cartItem.Item.Name,
+			UnitPrice: cartItem.Item.Price,
+			Quantity:  cartItem.Quantity,
+			LineTotal: lineTotal,
+		})
+	}
+	return lines, total, nil
+}
+
+// commitOrderStock removes each cart line's quantity from stock, converting the cart's reservations.
+// A line without enough stock fails with a 409 describing what is available.
+func commitOrderStock(tx *gorm.DB, order *models.Order, cartItems []models.CartItem) error {
+	for _, cartItem := range cartItems {
+		_, err := database.CommitReservedStock(tx, order.CartID, cartItem.ItemID, cartItem.Quantity, database.StockChange{
+			Reason:  models.StockReasonOrder,
+			OrderID: &order.ID,
+		})
+		if err == database.ErrInsufficientStock {
+			var item models.Item
+			tx.First(&item, cartItem.ItemID)
+			return &apiError{http.StatusConflict, gin.H{
+				"error":     "Not enough stock for " + item.Name,
+				"item_id":   cartItem.ItemID,
+				"requested": cartItem.Quantity,
+				"available": item.Stock - item.Reserved,
+			}}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ListOrders returns all orders
 func ListOrders(c *gin.Context) {
 	var orders []models.Order
-	if err := database.DB.Preload("User").Preload("Cart").Find(&orders).Error; err != nil {
+	if err := database.DB.Preload("User").Preload("Lines").Preload("StatusEvents", orderedEvents).Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
+	c.JSON(http.StatusOK, gin.H{"orders": models.NewOrderViews(orders)})
 }
 
 // GetUserOrders returns orders for the current user
@@ -96,30 +198,191 @@ func GetUserOrders(c *gin.Context) {
 	}
 
 	var orders []models.Order
-	if err := database.DB.Where("user_id = ?", user.ID).Preload("Cart").Find(&orders).Error; err != nil {
+	if err := database.DB.Where("user_id = ?", user.ID).Preload("Lines").Preload("StatusEvents", orderedEvents).Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user orders"})
 		return
 	}
 
-	// Load cart items for each order
-	for i := range orders {
-		if orders[i].Cart != nil {
-			var cartItems []models.CartItem
-			if err := database.DB.Where("cart_id = ?", orders[i].Cart.ID).Preload("Item").Find(&cartItems).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart items"})
-				return
-			}
+	c.JSON(http.StatusOK, gin.H{"orders": models.NewOrderViews(orders)})
+}
+
+// orderedEvents preloads an order's status history oldest first
+func orderedEvents(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at, id")
+}
+
+// transitionError turns a failed status change into the response explaining it
+func transitionError(order *models.Order, status string, err error) error {
+	switch err {
+	case database.ErrInvalidTransition:
+		return &apiError{http.StatusConflict, gin.H{
+			"error":            "Cannot move order from " + order.Status + " to " + status,
+			"status":           order.Status,
+			"allowed_statuses": models.OrderStatusTransitions[order.Status],
+		}}
+	case database.ErrOrderStatusChanged:
+		return &apiError{http.StatusConflict, gin.H{"error": "Order status changed, reload and try again"}}
+	}
+	return err
+}
+
+// cancelOrder cancels the order, puts its lines back in stock and refunds it if it was paid
+func cancelOrder(tx *gorm.DB, order *models.Order, actorID *uint, reason string) error {
+	paid := database.OrderWasPaid(tx, order.ID)
+
+	if _, err := database.TransitionOrder(tx, order, models.OrderStatusCancelled, actorID, reason); err != nil {
+		return transitionError(order, models.OrderStatusCancelled, err)
+	}
+	if err := tx.Model(order).Update("cancel_reason", reason).Error; err != nil {
+		return err
+	}
+
+	var lines []models.OrderLine
+	if err := tx.Where("order_id = ?", order.ID).Find(&lines).Error; err != nil {
+		return err
+	}
+	for _, line := range lines {
+		_, err := database.AdjustStock(tx, line.ItemID, line.Quantity, database.StockChange{
+			Reason:  models.StockReasonCancelled,
+			OrderID: &order.ID,
+			ActorID: actorID,
+		})
+		if err != nil && err != database.ErrItemNotFound {
+			return err
+		}
+	}
+
+	if paid {
+		refundReason := "Order cancelled"
+		if reason != "" {
+			refundReason += ": " + reason
+		}
+		return refundOrder(tx, order, actorID, refundReason)
+	}
+	return nil
+}
+
+// refundOrder returns the order's total through the payment gateway and marks it refunded.
+// The gateway is called last, so a failed refund rolls the whole change back. If saving the order
+// fails after the gateway paid, the refund's idempotency key makes a retry return the same refund.
+func refundOrder(tx *gorm.DB, order *models.Order, actorID *uint, reason string) error {
+	if !database.OrderWasPaid(tx, order.ID) {
+		return &apiError{http.StatusConflict, gin.H{"error": "Order was never paid"}}
+	}
+	if _, err := database.TransitionOrder(tx, order, models.OrderStatusRefunded, actorID, reason); err != nil {
+		return transitionError(order, models.OrderStatusRefunded, err)
+	}
+
+	reference, err := payment.Refund(order.ID, order.Total, reason)
+	if err != nil {
+		log.Printf("Refund of order %d failed: %v", order.ID, err)
+		return &apiError{http.StatusBadGateway, gin.H{"error": "Refund failed, the order was not changed"}}
+	}
+	order.RefundReference = reference
+	return tx.Model(order).Update("refund_reference", reference).Error
+}
+
+// UpdateOrderStatus moves an order to a new status if the transition table allows it.
+// Cancelling restores stock and refunds a paid order; refunding goes through the payment gateway
+// and requires orders:refund.
+func UpdateOrderStatus(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
 
-			// Extract items from cart items
-			var items []models.Item
-			for _, cartItem := range cartItems {
-				if cartItem.Item != nil {
-					items = append(items, *cartItem.Item)
-				}
+	var req models.UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.ValidOrderStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown order status"})
+		return
+	}
+
+	var order models.Order
+	if err := database.DB.First(&order, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	err := database.Transaction(database.DB, func(tx *gorm.DB) error {
+		// Anything that sends money back also needs the refund permission
+		refunds := req.Status == models.OrderStatusRefunded ||
+			(req.Status == models.OrderStatusCancelled && database.OrderWasPaid(tx, order.ID))
+		if refunds {
+			if status, body := middleware.CheckPermission(c, user, models.PermOrdersRefund); status != 0 {
+				return &apiError{status, body}
 			}
-			orders[i].Cart.Items = items
 		}
+
+		switch req.Status {
+		case models.OrderStatusCancelled:
+			return cancelOrder(tx, &order, &user.ID, req.Reason)
+		case models.OrderStatusRefunded:
+			return refundOrder(tx, &order, &user.ID, req.Reason)
+		}
+		_, err := database.TransitionOrder(tx, &order, req.Status, &user.ID, req.Reason)
+		return transitionError(&order, req.Status, err)
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update order status")
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
-} 
\ No newline at end of file
+	database.DB.Preload("Lines").Preload("StatusEvents", orderedEvents).First(&order, order.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order status updated successfully",
+		"order":   models.NewOrderView(&order),
+	})
+}
+
+// CancelOrder lets a customer cancel their own order before fulfilment starts
+func CancelOrder(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var req models.CancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var order models.Order
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	cancellable := false
+	for _, status := range models.CustomerCancellableStatuses {
+		if order.Status == status {
+			cancellable = true
+			break
+		}
+	}
+	if !cancellable {
+		c.JSON(http.StatusConflict, gin.H{"error": "Order can no longer be cancelled", "status": order.Status})
+		return
+	}
+
+	if err := database.Transaction(database.DB, func(tx *gorm.DB) error {
+		return cancelOrder(tx, &order, &user.ID, req.Reason)
+	}); err != nil {
+		respondError(c, err, "Failed to cancel order")
+		return
+	}
+
+	database.DB.Preload("Lines").Preload("StatusEvents", orderedEvents).First(&order, order.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order cancelled successfully",
+		"order":   models.NewOrderView(&order),
+	})
+}
\ No newline at end of file
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ecommerce-backend/database"
+	"ecommerce-backend/models"
+	"ecommerce-backend/payment"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// recordingGateway records the references the gateway returned for each idempotency key
+type recordingGateway struct {
+	payment.LogGateway
+	references map[string][]string
+}
+
+func (g *recordingGateway) Refund(idempotencyKey string, orderID uint, amount models.Money, reason string) (string, error) {
+	reference, err := g.LogGateway.Refund(idempotencyKey, orderID, amount, reason)
+	if err == nil {
+		g.references[idempotencyKey] = append(g.references[idempotencyKey], reference)
+	}
+	return reference, err
+}
+
+// callOrderHandler runs handler for user on the order with body as its JSON request
+func callOrderHandler(handler gin.HandlerFunc, user models.User, orderID uint, body interface{}) *httptest.ResponseRecorder {
+	return callHandler(func(c *gin.Context) {
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(orderID), 10)}}
+		handler(c)
+	}, user, body)
+}
+
+// placePaidOrder checks out a cart holding the items and marks the order paid
+func placePaidOrder(t *testing.T, itemIDs ...uint) (models.User, models.Order) {
+	t.Helper()
+	user, cart := createShopper(t, itemIDs...)
+	if w := callHandler(CreateOrder, user, models.CreateOrderRequest{CartID: cart.ID}); w.Code != http.StatusCreated {
+		t.Fatalf("create order: %d %s", w.Code, w.Body)
+	}
+	var order models.Order
+	database.DB.Where("cart_id = ?", cart.ID).First(&order)
+	if _, err := database.TransitionOrder(database.DB, &order, models.OrderStatusPaid, nil, ""); err != nil {
+		t.Fatalf("mark paid: %v", err)
+	}
+	return user, order
+}
+
+func TestCancelPaidOrderRetryAfterFailedSaveRefundsOnce(t *testing.T) {
+	setupTestDB(t)
+	gateway := &recordingGateway{references: make(map[string][]string)}
+	payment.Current = gateway
+	t.Cleanup(func() { payment.Current = &payment.LogGateway{} })
+	user, order := placePaidOrder(t, 1)
+
+	// The gateway pays out, then storing its reference fails and the cancellation rolls back
+	failSave := true
+	database.DB.Callback().Update().After("gorm:update").Register("test:fail_refund_reference", func(scope *gorm.Scope) {
+		if failSave && strings.Contains(scope.SQL, "refund_reference") {
+			scope.Err(errors.New("injected save failure"))
+		}
+	})
+	before := snapshot(t)
+
+	w := callOrderHandler(CancelOrder, user, order.ID, models.CancelOrderRequest{Reason: "changed my mind"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusInternalServerError, w.Body)
+	}
+	assertUnchanged(t, before)
+
+	failSave = false
+	w = callOrderHandler(CancelOrder, user, order.ID, models.CancelOrderRequest{Reason: "changed my mind"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("retry status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	var stored models.Order
+	database.DB.First(&stored, order.ID)
+	if stored.Status != models.OrderStatusRefunded || stored.RefundReference == "" {
+		t.Errorf("order = %q with reference %q, want refunded", stored.Status, stored.RefundReference)
+	}
+	references := gateway.references[payment.RefundKey(order.ID)]
+	if len(references) != 2 || references[1] != references[0] || stored.RefundReference != references[0] {
+		t.Errorf("gateway references %v, stored %q, want the retry to return the first refund", references, stored.RefundReference)
+	}
+}
+
+func TestUpdateOrderStatusRefundRequiresRefundPermission(t *testing.T) {
+	setupTestDB(t)
+	_, order := placePaidOrder(t, 1)
+
+	permissions, _ := database.FindPermissions([]string{models.PermOrdersWrite})
+	if err := database.DB.Create(&models.Role{Name: "fulfilment", Permissions: permissions}).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	clerk := models.User{Username: "clerk", Password: "x", Role: "fulfilment"}
+	admin := models.User{Username: "boss", Password: "x", Role: models.RoleAdmin}
+	database.DB.Create(&clerk)
+	database.DB.Create(&admin)
+
+	for _, status := range []string{models.OrderStatusRefunded, models.OrderStatusCancelled} {
+		w := callOrderHandler(UpdateOrderStatus, clerk, order.ID, models.UpdateOrderStatusRequest{Status: status})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s without orders:refund: status = %d, want %d: %s", status, w.Code, http.StatusForbidden, w.Body)
+		}
+	}
+	if w := callOrderHandler(UpdateOrderStatus, clerk, order.ID, models.UpdateOrderStatusRequest{Status: models.OrderStatusFulfilling}); w.Code != http.StatusOK {
+		t.Fatalf("fulfilling: status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := callOrderHandler(UpdateOrderStatus, admin, order.ID, models.UpdateOrderStatusRequest{Status: models.OrderStatusCancelled}); w.Code != http.StatusOK {
+		t.Fatalf("cancel with orders:refund: status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	var stored models.Order
+	database.DB.First(&stored, order.ID)
+	if stored.Status != models.OrderStatusRefunded {
+		t.Errorf("status = %q, want %q", stored.Status, models.OrderStatusRefunded)
+	}
+}
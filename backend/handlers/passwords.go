@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"ecommerce-backend/config"
+	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+	"ecommerce-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPassword mints a password reset token and sends it to the user
+func ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Respond identically whether or not the account exists
+	response := gin.H{"message": "If the account exists, a password reset token has been sent"}
+
+	var user models.User
+	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	// Only the most recently issued reset token stays usable
+	now := time.Now()
+	database.DB.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", now)
+
+	reset := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(config.AppConfig.Auth.PasswordResetTTLDuration()),
+	}
+	if err := database.DB.Create(&reset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+
+	notifyUser(&user, "Password reset", fmt.Sprintf(
+		"Use this token to reset your password: %s\nIt expires at %s.",
+		token, reset.ExpiresAt.Format(time.RFC1123)))
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword sets a new password using a reset token and ends all sessions
+func ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reset models.PasswordResetToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(req.Token)).First(&reset).Error; err != nil ||
+		reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, reset.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	if err := utils.ValidatePassword(req.NewPassword, user.Username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// Consume the token; a concurrent request that got here first wins
+	result := database.DB.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", reset.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	var sessions []models.Session
+	database.DB.Where("user_id = ? AND revoked_at IS NULL", user.ID).Find(&sessions)
+	if err := database.RevokeSessions(sessions, "password_reset"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// ChangePassword updates the current user's password and replaces all sessions with a fresh one
+func ChangePassword(c *gin.Context) {
+	contextUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	current, exists := middleware.GetSessionFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found in context"})
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, contextUser.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !utils.CheckPasswordHash(req.OldPassword, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if req.NewPassword == req.OldPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current password"})
+		return
+	}
+	if err := utils.ValidatePassword(req.NewPassword, user.Username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	if err := database.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	// Rotate sessions: every existing token dies, this device gets a new one
+	var sessions []models.Session
+	database.DB.Where("user_id = ? AND revoked_at IS NULL", user.ID).Find(&sessions)
+	if err := database.RevokeSessions(sessions, "password_changed"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	tokens, err := startSession(c, &user, current.DeviceLabel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Password changed successfully",
+		"token":              tokens.Token,
+		"expires_at":         tokens.ExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	})
+}
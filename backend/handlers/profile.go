@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxDisplayNameLength = 64
+
+var (
+	// phonePattern matches E.164 numbers once spaces and punctuation are removed
+	phonePattern    = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+	languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+)
+
+// GetProfile returns the current user's profile with cart and order summaries
+func GetProfile(c *gin.Context) {
+	contextUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, contextUser.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	profile := models.ProfileView{UserView: models.NewUserView(&user)}
+
+	var cart models.Cart
+	if err := database.DB.Where("user_id = ? AND status = ?", user.ID, models.CartStatusActive).First(&cart).Error; err == nil {
+		summary := models.CartSummary{ID: cart.ID, Status: cart.Status}
+		database.DB.Model(&models.CartItem{}).Where("cart_id = ?", cart.ID).
+			Select("COALESCE(SUM(quantity), 0)").Row().Scan(&summary.ItemCount)
+		profile.Cart = &summary
+	}
+
+	database.DB.Model(&models.Order{}).Where("user_id = ?", user.ID).Count(&profile.Orders.Count)
+	if profile.Orders.Count > 0 {
+		var last models.Order
+		if err := database.DB.Where("user_id = ?", user.ID).Order("created_at desc").First(&last).Error; err == nil {
+			profile.Orders.LastOrderAt = &last.CreatedAt
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"profile": profile})
+}
+
+// UpdateProfile applies a partial update to the current user's profile.
+// Changing the email address resets verification and sends a new token.
+func UpdateProfile(c *gin.Context) {
+	contextUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, contextUser.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "display_name must be at most 64 characters"})
+			return
+		}
+		if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "display_name contains invalid characters"})
+			return
+		}
+		updates["display_name"] = name
+	}
+
+	if req.Phone != nil {
+		phone := phoneSeparators.Replace(strings.TrimSpace(*req.Phone))
+		if phone != "" && !phonePattern.MatchString(phone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "phone must be in international format, e.g. +14155550123"})
+			return
+		}
+		updates["phone"] = phone
+	}
+
+	emailChanged := false
+	if req.Email != nil {
+		email := normalizeEmail(*req.Email)
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email must be a valid email address"})
+			return
+		}
+		if email != user.Email {
+			var count int
+			database.DB.Model(&models.User{}).Where("email = ? AND id <> ?", email, user.ID).Count(&count)
+			if count > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+				return
+			}
+			updates["email"] = email
+			updates["email_verified"] = false
+			updates["email_verified_at"] = nil
+			emailChanged = true
+		}
+	}
+
+	if req.Preferences != nil {
+		prefs := user.Preferences
+		if req.Preferences.Language != nil {
+			language := strings.TrimSpace(*req.Preferences.Language)
+			if language != "" && !languagePattern.MatchString(language) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "preferences.language must be a language tag such as en or en-US"})
+				return
+			}
+			prefs.Language = language
+		}
+		if req.Preferences.MarketingEmails != nil {
+			prefs.MarketingEmails = *req.Preferences.MarketingEmails
+		}
+		if req.Preferences.OrderUpdates != nil {
+			prefs.OrderUpdates = *req.Preferences.OrderUpdates
+		}
+		updates["preferences"] = prefs
+	}
+
+	if len(updates) > 0 {
+		updates["updated_at"] = time.Now()
+		if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+		database.DB.First(&user, user.ID)
+	}
+
+	if emailChanged {
+		if err := sendEmailVerification(&user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"user":    models.NewUserView(&user),
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+
+	"ecommerce-backend/database"
+	"ecommerce-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// ListPermissions returns every permission that can be granted to a role
+func ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"permissions": models.AllPermissions})
+}
+
+// ListRoles returns all roles with their permissions
+func ListRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := database.DB.Preload("Permissions").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// CreateRole handles custom role creation
+func CreateRole(c *gin.Context) {
+	var req models.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !roleNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role name must be 2-32 lowercase letters, digits or underscores"})
+		return
+	}
+	if database.RoleExists(req.Name) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+		return
+	}
+
+	permissions, ok := resolvePermissions(c, req.Permissions)
+	if !ok {
+		return
+	}
+
+	role := models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+	if err := database.DB.Create(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Role created successfully",
+		"role":    role,
+	})
+}
+
+// UpdateRole replaces a role's permissions and optionally its description
+func UpdateRole(c *gin.Context) {
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var role models.Role
+	if err := database.DB.Where("name = ?", c.Param("name")).First(&role).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	// Built-in roles are reset to their defaults on startup, which also keeps admins from
+	// locking themselves out of role management
+	if role.Builtin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in roles cannot be modified"})
+		return
+	}
+
+	permissions, ok := resolvePermissions(c, req.Permissions)
+	if !ok {
+		return
+	}
+
+	if req.Description != nil {
+		role.Description = *req.Description
+		database.DB.Model(&role).Update("description", role.Description)
+	}
+	if err := database.DB.Model(&role).Association("Permissions").Replace(permissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	role.Permissions = permissions
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"role":    role,
+	})
+}
+
+// DeleteRole removes a custom role that is no longer assigned to any user
+func DeleteRole(c *gin.Context) {
+	var role models.Role
+	if err := database.DB.Where("name = ?", c.Param("name")).First(&role).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if role.Builtin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in roles cannot be deleted"})
+		return
+	}
+
+	var count int
+	database.DB.Model(&models.User{}).Where("role = ?", role.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is still assigned to users"})
+		return
+	}
+
+	if err := database.DB.Model(&role).Association("Permissions").Clear().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	if err := database.DB.Delete(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// resolvePermissions validates permission names and loads their rows, writing an error response on failure
+func resolvePermissions(c *gin.Context, names []string) ([]models.Permission, bool) {
+	known := make(map[string]bool, len(models.AllPermissions))
+	for _, name := range models.AllPermissions {
+		known[name] = true
+	}
+	for _, name := range names {
+		if !known[name] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission: " + name})
+			return nil, false
+		}
+	}
+
+	permissions, err := database.FindPermissions(names)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch permissions"})
+		return nil, false
+	}
+	return permissions, true
+}
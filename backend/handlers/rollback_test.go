@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ecommerce-backend/config"
+	"ecommerce-backend/database"
+	"ecommerce-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// setupTestDB points the database package at a fresh, seeded in-memory SQLite database
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	config.LoadConfig()
+	config.AppConfig.Database.Type = "sqlite3"
+	config.AppConfig.Database.Name = "file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared"
+	config.AppConfig.Admin = config.AdminConfig{}
+	database.InitDatabase()
+	database.DB.LogMode(false)
+	t.Cleanup(func() { database.DB.Close() })
+}
+
+// createShopper creates a verified user with an active cart holding one unit of each item
+func createShopper(t *testing.T, itemIDs ...uint) (models.User, models.Cart) {
+	t.Helper()
+	user := models.User{Username: "shopper", Password: "x", Email: "shopper@example.com", EmailVerified: true, Role: models.RoleCustomer}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if len(itemIDs) > 0 {
+		if w := callHandler(AddToCart, user, models.AddToCartRequest{ItemIDs: itemIDs}); w.Code != http.StatusOK {
+			t.Fatalf("add to cart: %d %s", w.Code, w.Body)
+		}
+	}
+	cart, err := database.ActiveCart(database.DB, user.ID)
+	if err != nil {
+		t.Fatalf("active cart: %v", err)
+	}
+	return user, cart
+}
+
+// callHandler runs handler for user with body as its JSON request
+func callHandler(handler gin.HandlerFunc, user models.User, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user", user)
+	handler(c)
+	return w
+}
+
+// storeState is everything a failed cart or checkout request must leave untouched
+type storeState struct {
+	Users []struct {
+		ID     uint
+		CartID *uint
+	}
+	Carts []struct {
+		ID     uint
+		UserID uint
+		Status string
+	}
+	CartItems []struct {
+		CartID   uint
+		ItemID   uint
+		Quantity int
+	}
+	Items []struct {
+		ID       uint
+		Stock    int
+		Reserved int
+		Status   string
+	}
+	Reservations []struct {
+		CartID   uint
+		ItemID   uint
+		Quantity int
+	}
+	Orders       int
+	OrderLines   int
+	StatusEvents int
+	Adjustments  int
+}
+
+func snapshot(t *testing.T) storeState {
+	t.Helper()
+	var s storeState
+	db := database.DB
+	for _, err := range []error{
+		db.Table("users").Select("id, cart_id").Order("id").Scan(&s.Users).Error,
+		db.Table("carts").Select("id, user_id, status").Order("id").Scan(&s.Carts).Error,
+		db.Table("cart_items").Select("cart_id, item_id, quantity").Order("cart_id, item_id").Scan(&s.CartItems).Error,
+		db.Table("items").Select("id, stock, reserved, status").Order("id").Scan(&s.Items).Error,
+		db.Table("stock_reservations").Select("cart_id, item_id, quantity").Order("cart_id, item_id").Scan(&s.Reservations).Error,
+		db.Model(&models.Order{}).Count(&s.Orders).Error,
+		db.Model(&models.OrderLine{}).Count(&s.OrderLines).Error,
+		db.Model(&models.OrderStatusEvent{}).Count(&s.StatusEvents).Error,
+		db.Model(&models.StockAdjustment{}).Count(&s.Adjustments).Error,
+	} {
+		if err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+	}
+	return s
+}
+
+func assertUnchanged(t *testing.T, before storeState) {
+	t.Helper()
+	if after := snapshot(t); !reflect.DeepEqual(before, after) {
+		t.Errorf("store changed by failed request\nbefore: %+v\nafter:  %+v", before, after)
+	}
+}
+
+func TestAddToCartUnknownItemLeavesCartUnchanged(t *testing.T) {
+	setupTestDB(t)
+	user, _ := createShopper(t, 1)
+	before := snapshot(t)
+
+	w := callHandler(AddToCart, user, models.AddToCartRequest{ItemIDs: []uint{2, 999, 3}})
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+	assertUnchanged(t, before)
+}
+
+func TestCreateOrderOversoldLineLeavesCartUnchanged(t *testing.T) {
+	setupTestDB(t)
+	user, cart := createShopper(t, 1, 2)
+
+	// The second line's hold lapsed and its stock sold elsewhere, so only the first line can be committed
+	database.DB.Where("cart_id = ? AND item_id = ?", cart.ID, 2).Delete(&models.StockReservation{})
+	database.DB.Model(&models.Item{}).Where("id = ?", 2).Updates(map[string]interface{}{"stock": 0, "reserved": 0})
+	before := snapshot(t)
+
+	w := callHandler(CreateOrder, user, models.CreateOrderRequest{CartID: cart.ID})
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+	assertUnchanged(t, before)
+}
+
+func TestCreateOrderFailedLineInsertLeavesCartUnchanged(t *testing.T) {
+	setupTestDB(t)
+	user, cart := createShopper(t, 1, 2)
+	database.DB.Callback().Create().Before("gorm:create").Register("test:fail_order_lines", func(scope *gorm.Scope) {
+		if scope.TableName() == "order_lines" {
+			scope.Err(errors.New("injected order line failure"))
+		}
+	})
+	before := snapshot(t)
+
+	w := callHandler(CreateOrder, user, models.CreateOrderRequest{CartID: cart.ID})
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusInternalServerError, w.Body)
+	}
+	assertUnchanged(t, before)
+}
+
+func TestCreateOrderCommitsCart(t *testing.T) {
+	setupTestDB(t)
+	user, cart := createShopper(t, 1, 2)
+
+	w := callHandler(CreateOrder, user, models.CreateOrderRequest{CartID: cart.ID})
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	s := snapshot(t)
+	if s.Orders != 1 || s.OrderLines != 2 || len(s.CartItems) != 0 || len(s.Reservations) != 0 {
+		t.Errorf("unexpected store after checkout: %+v", s)
+	}
+	if s.Items[0].Stock != 24 || s.Items[0].Reserved != 0 || s.Items[1].Stock != 24 {
+		t.Errorf("stock not committed: %+v", s.Items[:2])
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ecommerce-backend/config"
+	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+	"ecommerce-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startSession records a new device session for the user and issues its first token pair
+func startSession(c *gin.Context, user *models.User, deviceLabel string) (*models.TokenResponse, error) {
+	if deviceLabel == "" {
+		deviceLabel = "Unknown device"
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:      user.ID,
+		DeviceLabel: deviceLabel,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(config.AppConfig.JWT.RefreshExpiryDuration()),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	return issueTokens(&session, user)
+}
+
+// issueTokens signs a new access token bound to the session and mints the next refresh token in its family
+func issueTokens(session *models.Session, user *models.User) (*models.TokenResponse, error) {
+	expiresAt := time.Now().Add(config.AppConfig.JWT.ExpiryDuration())
+	if expiresAt.After(session.ExpiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	token, err := utils.GenerateJWT(user.ID, session.ID, user.Username, user.Role, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the latest access token of a session is accepted
+	if err := database.DB.Model(session).Update("token_hash", utils.HashToken(token)).Error; err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	refresh := models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := database.DB.Create(&refresh).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refresh.ExpiresAt,
+	}, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access/refresh pair
+func RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var refresh models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(req.RefreshToken)).First(&refresh).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token", "code": "refresh_token_invalid"})
+		return
+	}
+
+	var session models.Session
+	if err := database.DB.First(&session, refresh.SessionID).Error; err != nil || session.RevokedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked", "code": "session_revoked"})
+		return
+	}
+
+	if time.Now().After(refresh.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has expired", "code": "refresh_token_expired"})
+		return
+	}
+
+	// Mark the token as used; if it already was, the family has leaked and is revoked
+	now := time.Now()
+	result := database.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", refresh.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		database.RevokeSessions([]models.Session{session}, "refresh_token_reused")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used", "code": "refresh_token_reused"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, session.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	tokens, err := issueTokens(&session, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// ListSessions returns the current user's active sessions
+func ListSessions(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	current, _ := middleware.GetSessionFromContext(c)
+
+	var sessions []models.Session
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Order("last_seen_at desc").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = current != nil && sessions[i].ID == current.ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession revokes one of the current user's sessions
+func RevokeSession(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var session models.Session
+	if err := database.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, user.ID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := database.RevokeSessions([]models.Session{session}, "session_revoked"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeOtherSessions revokes every session of the current user except the one making the request
+func RevokeOtherSessions(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	current, exists := middleware.GetSessionFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found in context"})
+		return
+	}
+
+	var sessions []models.Session
+	if err := database.DB.Where("user_id = ? AND id <> ? AND revoked_at IS NULL", user.ID, current.ID).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	if err := database.RevokeSessions(sessions, "session_revoked"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Other sessions revoked successfully",
+		"revoked": len(sessions),
+	})
+}
+
+// Logout ends the current session and revokes its access token
+func Logout(c *gin.Context) {
+	session, exists := middleware.GetSessionFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found in context"})
+		return
+	}
+
+	if err := database.RevokeSessions([]models.Session{*session}, "logout"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// ForceLogout revokes every session of the given user
+func ForceLogout(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var sessions []models.Session
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	if err := database.RevokeSessions(sessions, "forced_logout"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User logged out of all sessions",
+		"revoked": len(sessions),
+	})
+}
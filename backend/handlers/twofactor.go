@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"ecommerce-backend/config"
+	"ecommerce-backend/database"
+	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
+	"ecommerce-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxChallengeAttempts is how many codes may be tried against a login challenge
+	maxChallengeAttempts = 5
+)
+
+// EnrollTwoFactor generates a pending TOTP secret for the current user
+func EnrollTwoFactor(c *gin.Context) {
+	contextUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, contextUser.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	if err := database.DB.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(config.AppConfig.Auth.TOTPIssuer, user.Username, secret),
+	})
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves their app generates valid codes
+func ConfirmTwoFactor(c *gin.Context) {
+	contextUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, contextUser.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor enrollment has not been started"})
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, user.TOTPLastStep)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_enabled":   true,
+		"totp_last_step": step,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns off two-factor authentication after re-checking the password and a code
+func DisableTwoFactor(c *gin.Context) {
+	contextUser, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, contextUser.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if _, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, user.TOTPLastStep); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_enabled":   false,
+		"totp_secret":    "",
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// LoginTwoFactor completes a login challenge with a TOTP or recovery code and issues a session
+func LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+		return
+	}
+
+	var challenge models.LoginChallenge
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(req.ChallengeToken)).First(&challenge).Error; err != nil ||
+		challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge", "code": "challenge_invalid"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, challenge.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge", "code": "challenge_invalid"})
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		recordLoginAttempt(c, &user, user.Username, false, "locked")
+		respondTooManyAttempts(c, time.Until(*user.LockedUntil), "Account is temporarily locked")
+		return
+	}
+
+	// Each guess claims one of the challenge's attempts up front, so parallel guesses cannot exceed the limit
+	claim := database.DB.Model(&models.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", challenge.ID, maxChallengeAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge", "code": "challenge_invalid"})
+		return
+	}
+
+	verified := false
+	if req.Code != "" {
+		if step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, user.TOTPLastStep); ok {
+			database.DB.Model(&user).Update("totp_last_step", step)
+			verified = true
+		}
+	} else {
+		verified = useRecoveryCode(user.ID, req.RecoveryCode)
+	}
+
+	if !verified {
+		registerFailedLogin(&user)
+		recordLoginAttempt(c, &user, user.Username, false, "bad_second_factor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	// Consume the challenge so it cannot be completed twice
+	result := database.DB.Model(&models.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge", "code": "challenge_invalid"})
+		return
+	}
+
+	clearFailedLogins(&user)
+	recordLoginAttempt(c, &user, user.Username, true, "second_factor")
+	completeLogin(c, &user, challenge.DeviceLabel)
+}
+
+// startLoginChallenge records a pending two-factor login and returns its token
+func startLoginChallenge(user *models.User, deviceLabel string) (string, time.Time, error) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	challenge := models.LoginChallenge{
+		UserID:      user.ID,
+		TokenHash:   utils.HashToken(token),
+		DeviceLabel: deviceLabel,
+		ExpiresAt:   time.Now().Add(config.AppConfig.Auth.TwoFactorTTLDuration()),
+	}
+	if err := database.DB.Create(&challenge).Error; err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, challenge.ExpiresAt, nil
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and stores a fresh set, returning the plain codes
+func replaceRecoveryCodes(userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		recovery := models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(code),
+		}
+		if err := database.DB.Create(&recovery).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// useRecoveryCode consumes an unused recovery code, reporting whether it was valid
+func useRecoveryCode(userID uint, code string) bool {
+	hash := utils.HashToken(strings.ToLower(strings.TrimSpace(code)))
+	result := database.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected > 0
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"time"
 
 	"ecommerce-backend/database"
 	"ecommerce-backend/models"
@@ -25,17 +27,23 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	// Hash password
-	hashedPassword, err := utils.HashPassword(req.Password)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+	// Check if email is already registered
+	email := normalizeEmail(req.Email)
+	if err := database.DB.Where("email = ?", email).First(&existingUser).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	// Enforce password policy
+	if err := utils.ValidatePassword(req.Password, req.Username); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken()
+	// Hash password
+	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		return
 	}
 
@@ -43,7 +51,8 @@ func CreateUser(c *gin.Context) {
 	user := models.User{
 		Username: req.Username,
 		Password: hashedPassword,
-		Token:    token,
+		Role:     models.RoleCustomer,
+		Email:    email,
 	}
 
 	if err := database.DB.Create(&user).Error; err != nil {
@@ -52,24 +61,33 @@ func CreateUser(c *gin.Context) {
 	}
 
 	// Create cart for the user
-	cart := models.Cart{
-		UserID: user.ID,
-		Name:   "My Cart",
-		Status: "active",
-	}
-
-	if err := database.DB.Create(&cart).Error; err != nil {
+	cart, err := database.ActiveCart(database.DB, user.ID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cart"})
 		return
 	}
-
-	// Update user with cart ID
 	user.CartID = &cart.ID
-	database.DB.Save(&user)
+
+	// Send email verification; the account works but cannot order until verified
+	if err := sendEmailVerification(&user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	// Start a session for the registering device
+	tokens, err := startSession(c, &user, req.DeviceLabel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "User created successfully",
-		"user":    user,
+		"message":            "User created successfully",
+		"user":               models.NewUserView(&user),
+		"token":              tokens.Token,
+		"expires_at":         tokens.ExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"permissions":        database.RolePermissions(user.Role),
 	})
 }
 
@@ -81,33 +99,77 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Throttle IPs with too many recent failures
+	if retryAfter := ipRetryAfter(c.ClientIP()); retryAfter > 0 {
+		respondTooManyAttempts(c, retryAfter, "Too many failed login attempts")
+		return
+	}
+
 	// Find user by username
 	var user models.User
 	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+		recordLoginAttempt(c, nil, req.Username, false, "unknown_user")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
+	// Reject locked accounts without checking the password
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		recordLoginAttempt(c, &user, req.Username, false, "locked")
+		respondTooManyAttempts(c, time.Until(*user.LockedUntil), "Account is temporarily locked")
+		return
+	}
+
 	// Check password
 	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		registerFailedLogin(&user)
+		recordLoginAttempt(c, &user, req.Username, false, "bad_password")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	// Generate new token (single device login)
-	newToken, err := utils.GenerateToken()
+	// With two-factor enabled the failure count is only cleared once the second factor succeeds
+	if !user.TOTPEnabled {
+		clearFailedLogins(&user)
+		recordLoginAttempt(c, &user, req.Username, true, "")
+	}
+
+	beginLogin(c, &user, req.DeviceLabel)
+}
+
+// beginLogin continues a login whose first factor succeeded: accounts with two-factor
+// authentication get a challenge, everyone else gets a session
+func beginLogin(c *gin.Context, user *models.User, deviceLabel string) {
+	if user.TOTPEnabled {
+		challengeToken, expiresAt, err := startLoginChallenge(user, deviceLabel)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor challenge"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challengeToken,
+			"expires_at":          expiresAt,
+		})
+		return
+	}
+
+	completeLogin(c, user, deviceLabel)
+}
+
+// completeLogin starts a session for an authenticated user and writes the login response
+func completeLogin(c *gin.Context, user *models.User, deviceLabel string) {
+	// Start a new session; existing sessions on other devices stay valid
+	tokens, err := startSession(c, user, deviceLabel)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	// Update user token
-	user.Token = newToken
-	database.DB.Save(&user)
-
 	c.JSON(http.StatusOK, models.LoginResponse{
-		Token: newToken,
-		User:  user,
+		TokenResponse: *tokens,
+		User:          models.NewUserView(user),
+		Permissions:   database.RolePermissions(user.Role),
 	})
 }
 
@@ -119,5 +181,41 @@ func ListUsers(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"users": users})
-} 
\ No newline at end of file
+	c.JSON(http.StatusOK, gin.H{"users": models.NewUserViews(users)})
+}
+
+// UpdateUserRole changes a user's role and ends their sessions so the new role takes effect
+func UpdateUserRole(c *gin.Context) {
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !database.RoleExists(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	var sessions []models.Session
+	database.DB.Where("user_id = ? AND revoked_at IS NULL", user.ID).Find(&sessions)
+	if err := database.RevokeSessions(sessions, "role_changed"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User role updated successfully",
+		"user":    models.NewUserView(&user),
+	})
+}
@@ -0,0 +1,335 @@
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ecommerce-backend/config"
+)
+
+// OIDCProvider implements Provider for OpenID Connect using the authorization-code flow with PKCE
+type OIDCProvider struct {
+	cfg    config.OIDCProviderConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+}
+
+// discoveryDocument is the subset of openid-configuration used by OIDCProvider
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims are the ID token claims checked during Exchange
+type idTokenClaims struct {
+	Issuer        string          `json:"iss"`
+	Subject       string          `json:"sub"`
+	Audience      json.RawMessage `json:"aud"`
+	ExpiresAt     int64           `json:"exp"`
+	Nonce         string          `json:"nonce"`
+	Email         string          `json:"email"`
+	EmailVerified interface{}     `json:"email_verified"`
+	Name          string          `json:"name"`
+}
+
+// NewOIDCProvider builds a provider, discovering any endpoints missing from cfg
+func NewOIDCProvider(ctx context.Context, cfg config.OIDCProviderConfig) (*OIDCProvider, error) {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("client ID and redirect URL are required")
+	}
+	// ID tokens are only accepted from the configured issuer, even when endpoints are set explicitly
+	if cfg.Issuer == "" {
+		return nil, errors.New("issuer is required")
+	}
+
+	p := &OIDCProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.JWKSURL == "" {
+		var doc discoveryDocument
+		if err := p.getJSON(ctx, strings.TrimSuffix(cfg.Issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+			return nil, fmt.Errorf("discovery failed: %w", err)
+		}
+		if p.cfg.AuthURL == "" {
+			p.cfg.AuthURL = doc.AuthorizationEndpoint
+		}
+		if p.cfg.TokenURL == "" {
+			p.cfg.TokenURL = doc.TokenEndpoint
+		}
+		if p.cfg.JWKSURL == "" {
+			p.cfg.JWKSURL = doc.JWKSURI
+		}
+	}
+
+	return p, nil
+}
+
+// Name returns the configured provider name
+func (p *OIDCProvider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL returns the authorization endpoint URL with state, nonce and PKCE challenge
+func (p *OIDCProvider) AuthCodeURL(req AuthRequest) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", req.State)
+	params.Set("nonce", req.Nonce)
+	params.Set("code_challenge", req.CodeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.cfg.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.cfg.AuthURL + sep + params.Encode()
+}
+
+// Exchange redeems the code at the token endpoint and verifies the returned ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, err
+	}
+	if tokenResp.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, tokenResp.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	return &ExternalIdentity{
+		Provider:      p.cfg.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+// verifyIDToken checks the ID token signature, issuer, audience and expiry
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, token string) (*idTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id_token")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed id_token header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, errors.New("malformed id_token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed id_token signature")
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+
+	switch header.Alg {
+	case "RS256":
+		digest := sha256.Sum256(signingInput)
+		key, err := p.publicKey(ctx, header.Kid, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			// The provider may have rotated keys under the same kid
+			if key, err = p.publicKey(ctx, header.Kid, true); err != nil {
+				return nil, err
+			}
+			if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+				return nil, errors.New("invalid id_token signature")
+			}
+		}
+	case "HS256":
+		if p.cfg.ClientSecret == "" {
+			return nil, errors.New("HS256 id_token requires a client secret")
+		}
+		mac := hmac.New(sha256.New, []byte(p.cfg.ClientSecret))
+		mac.Write(signingInput)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, errors.New("invalid id_token signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported id_token algorithm %q", header.Alg)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed id_token payload")
+	}
+	var claims idTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed id_token payload")
+	}
+
+	if p.cfg.Issuer == "" || strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(p.cfg.Issuer, "/") {
+		return nil, errors.New("id_token issuer mismatch")
+	}
+	if !audienceContains(claims.Audience, p.cfg.ClientID) {
+		return nil, errors.New("id_token audience mismatch")
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, errors.New("id_token has expired")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("id_token has no subject")
+	}
+
+	return &claims, nil
+}
+
+// audienceContains reports whether the aud claim, a string or array, includes clientID
+func audienceContains(raw json.RawMessage, clientID string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == clientID
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err == nil {
+		for _, aud := range many {
+			if aud == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// publicKey returns the JWKS key with the given ID, fetching the key set on a cache miss or when refresh is set
+func (p *OIDCProvider) publicKey(ctx context.Context, kid string, refresh bool) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !refresh {
+		if key, ok := p.lookupKey(kid); ok {
+			return key, nil
+		}
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.cfg.JWKSURL, &jwks); err != nil {
+		return nil, fmt.Errorf("fetching JWKS: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	p.keys = keys
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("no JWKS key matches id_token")
+}
+
+// lookupKey finds a cached key by ID, or the only key when the token names none
+func (p *OIDCProvider) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// getJSON fetches url and decodes the JSON response into v
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,92 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+
+	"ecommerce-backend/config"
+)
+
+// ErrUnknownProvider is returned when no provider is registered under a name
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// ExternalIdentity is the user information asserted by an identity provider
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// AuthRequest carries the per-login values bound into the authorization redirect
+type AuthRequest struct {
+	State         string
+	Nonce         string
+	CodeChallenge string
+}
+
+// Provider is an external identity provider using the authorization-code flow
+type Provider interface {
+	// Name returns the identifier used in routes and LinkedIdentity rows
+	Name() string
+	// AuthCodeURL returns the URL the user is sent to for authentication
+	AuthCodeURL(req AuthRequest) string
+	// Exchange redeems an authorization code and returns the verified identity
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error)
+}
+
+// providers holds the registered providers by name
+var providers = map[string]Provider{}
+
+// Register makes a provider available under its name
+func Register(p Provider) {
+	providers[p.Name()] = p
+}
+
+// Get returns the provider registered under name
+func Get(name string) (Provider, error) {
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Names lists the registered provider names
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// InitProviders registers an OIDC provider for each entry in the configuration
+func InitProviders() {
+	for _, cfg := range config.AppConfig.OIDC {
+		p, err := NewOIDCProvider(context.Background(), cfg)
+		if err != nil {
+			log.Printf("Identity provider %s not available: %v", cfg.Name, err)
+			continue
+		}
+		Register(p)
+		log.Printf("Identity provider %s registered", cfg.Name)
+	}
+}
+
+// NewPKCE returns a random PKCE code verifier and its S256 challenge
+func NewPKCE() (verifier, challenge string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(bytes)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier, challenge, nil
+}
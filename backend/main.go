@@ -6,7 +6,10 @@ import (
 
 	"ecommerce-backend/config"
 	"ecommerce-backend/database"
+	"ecommerce-backend/identity"
+	"ecommerce-backend/notify"
 	"ecommerce-backend/routes"
+	"ecommerce-backend/utils"
 )
 
 func main() {
@@ -14,10 +17,26 @@ func main() {
 	config.LoadConfig()
 	log.Println("Configuration loaded successfully")
 
+	// Load common password denylist
+	if path := config.AppConfig.Auth.PasswordDenylistFile; path != "" {
+		if err := utils.LoadPasswordDenylist(path); err != nil {
+			log.Printf("Password denylist not loaded: %v", err)
+		}
+	}
+
 	// Initialize database
 	database.InitDatabase()
 	log.Println("Database initialized successfully")
 
+	// Release cart stock reservations once they expire
+	database.StartReservationSweeper(config.AppConfig.Store.ReservationSweepIntervalDuration())
+
+	// Initialize notifications
+	notify.InitNotifier()
+
+	// Register external identity providers
+	identity.InitProviders()
+
 	// Setup routes
 	r := routes.SetupRoutes()
 	log.Println("Routes configured successfully")
@@ -3,16 +3,23 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"ecommerce-backend/database"
 	"ecommerce-backend/models"
+	"ecommerce-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates the user token and sets the user in the context
+// AuthMiddleware validates the user token, or an X-API-Key header, and sets the user in the context
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -35,20 +42,72 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Find user by token
-		var user models.User
-		if err := database.DB.Where("token = ?", token).First(&user).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		// Verify token signature and expiry
+		claims, err := utils.ParseJWT(token)
+		if err != nil {
+			abortWithTokenError(c, err)
+			return
+		}
+
+		// Reject tokens killed by logout or forced logout
+		tokenHash := utils.HashToken(token)
+		if database.IsTokenRevoked(tokenHash) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked", "code": "token_revoked"})
 			c.Abort()
 			return
 		}
 
-		// Set user in context
+		// Resolve the session the token was issued for
+		var session models.Session
+		if err := database.DB.Where("id = ? AND user_id = ?", claims.SessionID, claims.UserID).First(&session).Error; err != nil ||
+			session.TokenHash != tokenHash {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found", "code": "session_invalid"})
+			c.Abort()
+			return
+		}
+		if session.RevokedAt != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked", "code": "session_revoked"})
+			c.Abort()
+			return
+		}
+		if time.Now().After(session.ExpiresAt) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has expired", "code": "session_expired"})
+			c.Abort()
+			return
+		}
+
+		// Record activity, at most once per minute to limit writes
+		if time.Since(session.LastSeenAt) > time.Minute {
+			session.LastSeenAt = time.Now()
+			database.DB.Model(&session).Update("last_seen_at", session.LastSeenAt)
+		}
+
+		user := models.User{
+			ID:       claims.UserID,
+			Username: claims.Username,
+			Role:     claims.Role,
+		}
+
+		// Set user and session in context
 		c.Set("user", user)
+		c.Set("session", session)
 		c.Next()
 	}
 }
 
+// abortWithTokenError rejects the request with an error code matching the token failure
+func abortWithTokenError(c *gin.Context, err error) {
+	switch err {
+	case utils.ErrTokenExpired:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired", "code": "token_expired"})
+	case utils.ErrTokenSignature:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token signature", "code": "token_invalid_signature"})
+	default:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token", "code": "token_invalid"})
+	}
+	c.Abort()
+}
+
 // GetUserFromContext extracts the user from the gin context
 func GetUserFromContext(c *gin.Context) (*models.User, bool) {
 	userInterface, exists := c.Get("user")
@@ -62,4 +121,19 @@ func GetUserFromContext(c *gin.Context) (*models.User, bool) {
 	}
 
 	return &user, true
-} 
\ No newline at end of file
+}
+
+// GetSessionFromContext extracts the current session from the gin context
+func GetSessionFromContext(c *gin.Context) (*models.Session, bool) {
+	sessionInterface, exists := c.Get("session")
+	if !exists {
+		return nil, false
+	}
+
+	session, ok := sessionInterface.(models.Session)
+	if !ok {
+		return nil, false
+	}
+
+	return &session, true
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"ecommerce-backend/database"
+	"ecommerce-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission allows the request through only if the authenticated user's role grants perm
+// and, for API key requests, the key was granted perm as a scope.
+// It must be registered after AuthMiddleware.
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+			c.Abort()
+			return
+		}
+
+		if status, body := CheckPermission(c, user, perm); status != 0 {
+			c.JSON(status, body)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// CheckPermission reports whether the request may use perm, for handlers whose required permission
+// depends on the request. It returns 0 if allowed, otherwise the status and body to respond with.
+func CheckPermission(c *gin.Context, user *models.User, perm string) (int, gin.H) {
+	if !database.HasPermission(user.Role, perm) {
+		return http.StatusForbidden, gin.H{"error": "Insufficient permissions", "permission": perm}
+	}
+	if key, ok := GetAPIKeyFromContext(c); ok && !key.HasScope(perm) {
+		return http.StatusForbidden, gin.H{"error": "API key lacks required scope", "scope": perm}
+	}
+	return 0, nil
+}
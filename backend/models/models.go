@@ -1,15 +1,168 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 )
 
+// Built-in user roles
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+)
+
+// Item statuses; an item is out of stock whenever its stock reaches zero
+const (
+	ItemStatusAvailable  = "available"
+	ItemStatusOutOfStock = "out_of_stock"
+)
+
+// Cart statuses; a user has at most one active cart, and placing an order converts it
+const (
+	CartStatusActive    = "active"
+	CartStatusConverted = "converted"
+)
+
+// Order statuses; an order starts awaiting payment and moves only along OrderStatusTransitions
+const (
+	OrderStatusPendingPayment = "pending_payment"
+	OrderStatusPaid           = "paid"
+	OrderStatusFulfilling     = "fulfilling"
+	OrderStatusShipped        = "shipped"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+	OrderStatusRefunded       = "refunded"
+
+	// OrderStatusLegacy marks orders placed before statuses were tracked; their history is unknown,
+	// so they cannot be moved on or cancelled
+	OrderStatusLegacy = "legacy"
+)
+
+// OrderStatusTransitions maps each order status to the statuses it may move to
+var OrderStatusTransitions = map[string][]string{
+	OrderStatusPendingPayment: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:           {OrderStatusFulfilling, OrderStatusCancelled},
+	OrderStatusFulfilling:     {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:        {OrderStatusDelivered},
+	OrderStatusDelivered:      {OrderStatusRefunded},
+	OrderStatusCancelled:      {OrderStatusRefunded},
+	OrderStatusRefunded:       {},
+	OrderStatusLegacy:         {},
+}
+
+// CustomerCancellableStatuses lists the statuses in which customers may cancel their own orders
+var CustomerCancellableStatuses = []string{OrderStatusPendingPayment, OrderStatusPaid}
+
+// ValidOrderStatus reports whether status is a known order status
+func ValidOrderStatus(status string) bool {
+	_, ok := OrderStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range OrderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Reasons recorded in the stock ledger
+const (
+	StockReasonRestock    = "restock"
+	StockReasonCorrection = "correction"
+	StockReasonDamaged    = "damaged"
+	StockReasonReturn     = "return"
+	StockReasonOrder      = "order"
+	StockReasonCancelled  = "order_cancelled"
+)
+
+// ManualStockReasons lists the reasons accepted by the stock adjustment endpoint
+var ManualStockReasons = []string{
+	StockReasonRestock,
+	StockReasonCorrection,
+	StockReasonDamaged,
+	StockReasonReturn,
+}
+
+// Permissions that can be granted to roles
+const (
+	PermItemsWrite   = "items:write"
+	PermOrdersRead   = "orders:read"
+	PermOrdersWrite  = "orders:write"
+	PermOrdersRefund = "orders:refund"
+	PermUsersRead    = "users:read"
+	PermUsersWrite   = "users:write"
+	PermCartsRead    = "carts:read"
+	PermRolesManage  = "roles:manage"
+)
+
+// AllPermissions lists every permission known to the application
+var AllPermissions = []string{
+	PermItemsWrite,
+	PermOrdersRead,
+	PermOrdersWrite,
+	PermOrdersRefund,
+	PermUsersRead,
+	PermUsersWrite,
+	PermCartsRead,
+	PermRolesManage,
+}
+
+// API key scopes for the key owner's own cart and orders; keys may also carry any permission
+const (
+	ScopeCartRead      = "cart:read"
+	ScopeCartWrite     = "cart:write"
+	ScopeOrdersPlace   = "orders:place"
+	ScopeOrdersHistory = "orders:history"
+)
+
+// APIKeyScopes lists every scope an API key may be granted
+var APIKeyScopes = append([]string{
+	ScopeCartRead,
+	ScopeCartWrite,
+	ScopeOrdersPlace,
+	ScopeOrdersHistory,
+}, AllPermissions...)
+
+// DefaultRolePermissions maps the built-in roles to the permissions they are seeded with
+var DefaultRolePermissions = map[string][]string{
+	RoleCustomer: {},
+	RoleStaff:    {PermItemsWrite, PermOrdersRead, PermUsersRead},
+	RoleAdmin:    AllPermissions,
+}
+
 // User represents a user account
 type User struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
 	Username  string    `json:"username" gorm:"unique;not null"`
 	Password  string    `json:"-" gorm:"not null"` // "-" means this field won't be included in JSON
-	Token     string    `json:"token" gorm:"unique"`
+	Role      string    `json:"role" gorm:"default:'customer'"`
+
+	// Profile details editable by the user
+	DisplayName string          `json:"display_name"`
+	Phone       string          `json:"phone"`
+	Preferences UserPreferences `json:"preferences" gorm:"type:text"`
+
+	// Contact details; orders require a verified email
+	Email           string     `json:"email" gorm:"index"`
+	EmailVerified   bool       `json:"email_verified" gorm:"default:false"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// Brute-force protection state
+	FailedLogins int        `json:"-" gorm:"default:0"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+
+	// Two-factor authentication state; TOTPSecret is pending until TOTPEnabled is set
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"default:false"`
+	TOTPLastStep int64  `json:"-" gorm:"default:0"`
 	CartID    *uint     `json:"cart_id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -19,38 +172,121 @@ type User struct {
 	Orders []Order  `json:"orders,omitempty" gorm:"foreignkey:UserID"`
 }
 
+// Address represents a postal address in a user's address book
+type Address struct {
+	ID              uint      `json:"id" gorm:"primary_key"`
+	UserID          uint      `json:"user_id" gorm:"not null;index"`
+	Name            string    `json:"name" gorm:"not null"`
+	Line1           string    `json:"line1" gorm:"not null"`
+	Line2           string    `json:"line2"`
+	City            string    `json:"city" gorm:"not null"`
+	Region          string    `json:"region"`
+	PostalCode      string    `json:"postal_code"`
+	Country         string    `json:"country" gorm:"not null"` // ISO 3166-1 alpha-2
+	Phone           string    `json:"phone"`
+	DefaultShipping bool      `json:"default_shipping" gorm:"default:false"`
+	DefaultBilling  bool      `json:"default_billing" gorm:"default:false"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// UserPreferences holds a user's settings; it is stored as JSON in a single column
+type UserPreferences struct {
+	Language        string `json:"language"`
+	MarketingEmails bool   `json:"marketing_emails"`
+	OrderUpdates    bool   `json:"order_updates"`
+}
+
+// Value implements driver.Valuer
+func (p UserPreferences) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (p *UserPreferences) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = UserPreferences{}
+		return nil
+	case string:
+		if v == "" {
+			*p = UserPreferences{}
+			return nil
+		}
+		return json.Unmarshal([]byte(v), p)
+	case []byte:
+		if len(v) == 0 {
+			*p = UserPreferences{}
+			return nil
+		}
+		return json.Unmarshal(v, p)
+	}
+	return errors.New("unsupported type for UserPreferences")
+}
+
 // Item represents a product/item in the store
 type Item struct {
-	ID        uint      `json:"id" gorm:"primary_key"`
-	Name      string    `json:"name" gorm:"not null"`
-	Status    string    `json:"status" gorm:"default:'available'"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uint      `json:"id" gorm:"primary_key"`
+	Name        string    `json:"name" gorm:"not null"`
+	Status      string    `json:"status" gorm:"default:'available'"`
+	Price       Money     `json:"price" gorm:"embedded;embedded_prefix:price_"`
+	Stock       int       `json:"stock" gorm:"not null;default:0"`
+	Reserved    int       `json:"reserved" gorm:"not null;default:0"` // units held by carts
+	MaxPerOrder int       `json:"max_per_order" gorm:"default:0"` // 0 means the store-wide cart limit
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 	
 	// Relationships
 	CartItems []CartItem `json:"cart_items,omitempty" gorm:"foreignkey:ItemID"`
 }
 
-// Cart represents a user's shopping cart
+// StockAdjustment is a ledger entry recording a change to an item's stock
+type StockAdjustment struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	ItemID     uint      `json:"item_id" gorm:"not null;index"`
+	Delta      int       `json:"delta"`
+	StockAfter int       `json:"stock_after"`
+	Reason     string    `json:"reason" gorm:"not null"`
+	Note       string    `json:"note,omitempty"`
+	OrderID    *uint     `json:"order_id,omitempty" gorm:"index"`
+	ActorID    *uint     `json:"actor_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Cart represents one of a user's shopping carts
 type Cart struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
-	UserID    uint      `json:"user_id" gorm:"not null;unique"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
 	Name      string    `json:"name"`
 	Status    string    `json:"status" gorm:"default:'active'"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	
 	// Relationships
-	User      *User       `json:"user,omitempty" gorm:"foreignkey:UserID"`
-	Items     []Item      `json:"items,omitempty" gorm:"many2many:cart_items;"`
-	CartItems []CartItem  `json:"cart_items,omitempty" gorm:"foreignkey:CartID"`
-	Order     *Order      `json:"order,omitempty" gorm:"foreignkey:CartID"`
+	User         *User              `json:"user,omitempty" gorm:"foreignkey:UserID"`
+	Items        []Item             `json:"items,omitempty" gorm:"many2many:cart_items;"`
+	CartItems    []CartItem         `json:"cart_items,omitempty" gorm:"foreignkey:CartID"`
+	Order        *Order             `json:"order,omitempty" gorm:"foreignkey:CartID"`
+	Reservations []StockReservation `json:"-" gorm:"foreignkey:CartID"`
+}
+
+// StockReservation holds units of an item for a cart until ExpiresAt
+type StockReservation struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	CartID    uint      `json:"cart_id" gorm:"not null;unique_index:idx_reservation_cart_item"`
+	ItemID    uint      `json:"item_id" gorm:"not null;unique_index:idx_reservation_cart_item"`
+	Quantity  int       `json:"quantity" gorm:"not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // CartItem represents the junction table between Cart and Item
 type CartItem struct {
 	CartID    uint      `json:"cart_id" gorm:"primary_key"`
 	ItemID    uint      `json:"item_id" gorm:"primary_key"`
+	Quantity  int       `json:"quantity" gorm:"not null;default:1"`
 	CreatedAt time.Time `json:"created_at"`
 	
 	// Relationships
@@ -63,38 +299,348 @@ type Order struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
 	CartID    uint      `json:"cart_id" gorm:"not null;unique"`
 	UserID    uint      `json:"user_id" gorm:"not null"`
+	Total     Money     `json:"total" gorm:"embedded;embedded_prefix:total_"`
+	Status    string    `json:"status" gorm:"not null;default:'pending_payment'"`
+
+	// Set when the order is cancelled or refunded
+	CancelReason    string `json:"cancel_reason,omitempty"`
+	RefundReference string `json:"refund_reference,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	
 	// Relationships
-	Cart *Cart `json:"cart,omitempty" gorm:"foreignkey:CartID"`
-	User *User `json:"user,omitempty" gorm:"foreignkey:UserID"`
+	Cart         *Cart              `json:"cart,omitempty" gorm:"foreignkey:CartID"`
+	User         *User              `json:"user,omitempty" gorm:"foreignkey:UserID"`
+	Lines        []OrderLine        `json:"lines,omitempty" gorm:"foreignkey:OrderID"`
+	StatusEvents []OrderStatusEvent `json:"status_events,omitempty" gorm:"foreignkey:OrderID"`
+}
+
+// OrderStatusEvent records one change of an order's status and who made it
+type OrderStatusEvent struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	OrderID    uint      `json:"order_id" gorm:"not null;index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	ActorID    *uint     `json:"actor_id,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OrderLine is a snapshot of an item as it was purchased, so order history survives catalog changes
+type OrderLine struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	OrderID   uint      `json:"order_id" gorm:"not null;index"`
+	ItemID    uint      `json:"item_id" gorm:"not null"`
+	Name      string    `json:"name" gorm:"not null"`
+	UnitPrice Money     `json:"unit_price" gorm:"embedded;embedded_prefix:unit_price_"`
+	Quantity  int       `json:"quantity" gorm:"not null"`
+	LineTotal Money     `json:"line_total" gorm:"embedded;embedded_prefix:line_total_"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session represents a logged-in device for a user
+type Session struct {
+	ID          uint       `json:"id" gorm:"primary_key"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash   string     `json:"-" gorm:"index"`
+	DeviceLabel string     `json:"device_label"`
+	IPAddress   string     `json:"ip_address"`
+	UserAgent   string     `json:"user_agent"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+
+	// Current marks the session used for the request; it is not persisted
+	Current bool `json:"current" gorm:"-"`
+}
+
+// RefreshToken represents a single-use refresh token; all tokens of a session form one rotation family
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	SessionID uint       `json:"session_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"unique;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RevokedToken records an access token that must be rejected before its natural expiry
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TokenHash string    `json:"-" gorm:"unique;not null"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Reason    string    `json:"reason"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginAttempt records a login attempt for brute-force detection and auditing
+type LoginAttempt struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    *uint     `json:"user_id" gorm:"index"`
+	Username  string    `json:"username"`
+	IPAddress string    `json:"ip_address" gorm:"index"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// EmailVerificationToken represents a single-use token proving the user can receive mail at Email
+type EmailVerificationToken struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Email     string     `json:"email" gorm:"not null"`
+	TokenHash string     `json:"-" gorm:"unique;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RecoveryCode represents a hashed one-time code that can replace a TOTP code
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginChallenge represents a password-verified login awaiting its second factor
+type LoginChallenge struct {
+	ID          uint       `json:"id" gorm:"primary_key"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash   string     `json:"-" gorm:"unique;not null"`
+	DeviceLabel string     `json:"device_label"`
+	Attempts    int        `json:"attempts" gorm:"default:0"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// LinkedIdentity connects a user to an account at an external identity provider
+type LinkedIdentity struct {
+	ID          uint       `json:"id" gorm:"primary_key"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Provider    string     `json:"provider" gorm:"not null;unique_index:idx_identity_provider_subject"`
+	Subject     string     `json:"subject" gorm:"not null;unique_index:idx_identity_provider_subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OAuthState holds the server-side half of an in-flight authorization-code login
+type OAuthState struct {
+	ID           uint       `json:"id" gorm:"primary_key"`
+	StateHash    string     `json:"-" gorm:"unique;not null"`
+	Provider     string     `json:"provider" gorm:"not null"`
+	CodeVerifier string     `json:"-" gorm:"not null"`
+	Nonce        string     `json:"-" gorm:"not null"`
+	LinkUserID   *uint      `json:"link_user_id,omitempty"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// PasswordResetToken represents a single-use token for resetting a forgotten password
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"unique;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// APIKey represents a long-lived credential for server-to-server access on behalf of a user.
+// The key is presented as "<prefix>.<secret>"; only the secret's hash is stored.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	CreatedBy  uint       `json:"created_by"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"unique;not null"`
+	SecretHash string     `json:"-" gorm:"not null"`
+	Scopes     string     `json:"scopes"` // space-separated
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range strings.Fields(k.Scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission represents a named capability such as items:write
+type Permission struct {
+	ID   uint   `json:"id" gorm:"primary_key"`
+	Name string `json:"name" gorm:"unique;not null"`
+}
+
+// Role represents a named set of permissions assignable to users
+type Role struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	Name        string    `json:"name" gorm:"unique;not null"`
+	Description string    `json:"description"`
+	Builtin     bool      `json:"builtin"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
 }
 
 // Request/Response structures
 
 // LoginRequest represents the login request payload
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DeviceLabel string `json:"device_label"`
+}
+
+// TokenResponse represents an issued access/refresh token pair
+type TokenResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	TokenResponse
+	User        UserView `json:"user"`
+	Permissions []string `json:"permissions"`
+}
+
+// RefreshTokenRequest represents the token refresh request
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // CreateUserRequest represents the user creation request
 type CreateUserRequest struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	Email       string `json:"email" binding:"required,email"`
+	DeviceLabel string `json:"device_label"`
+}
+
+// UpdateProfileRequest represents a partial profile update; omitted fields are left unchanged
+type UpdateProfileRequest struct {
+	DisplayName *string                   `json:"display_name"`
+	Email       *string                   `json:"email"`
+	Phone       *string                   `json:"phone"`
+	Preferences *UpdatePreferencesRequest `json:"preferences"`
+}
+
+// UpdatePreferencesRequest represents a partial preferences update
+type UpdatePreferencesRequest struct {
+	Language        *string `json:"language"`
+	MarketingEmails *bool   `json:"marketing_emails"`
+	OrderUpdates    *bool   `json:"order_updates"`
+}
+
+// AddressRequest represents the address creation and replacement request
+type AddressRequest struct {
+	Name            string `json:"name" binding:"required,max=100"`
+	Line1           string `json:"line1" binding:"required,max=200"`
+	Line2           string `json:"line2" binding:"max=200"`
+	City            string `json:"city" binding:"required,max=100"`
+	Region          string `json:"region" binding:"max=100"`
+	PostalCode      string `json:"postal_code"`
+	Country         string `json:"country" binding:"required"`
+	Phone           string `json:"phone"`
+	DefaultShipping bool   `json:"default_shipping"`
+	DefaultBilling  bool   `json:"default_billing"`
+}
+
+// CompleteLinkIdentityRequest carries the code and state an identity provider returned for a linking flow
+type CompleteLinkIdentityRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// VerifyEmailRequest represents the email verification confirmation
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UpdateUserRoleRequest represents the user role change request
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ForgotPasswordRequest represents the password reset request
+type ForgotPasswordRequest struct {
 	Username string `json:"username" binding:"required"`
+}
+
+// ResetPasswordRequest represents the password reset confirmation
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangePasswordRequest represents the authenticated password change request
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// TwoFactorCodeRequest represents a request carrying a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the request to turn off two-factor authentication
+type DisableTwoFactorRequest struct {
 	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest completes a login challenge with a TOTP or recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// CreateRoleRequest represents the custom role creation request
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest represents the role update request
+type UpdateRoleRequest struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// CreateAPIKeyRequest represents the API key creation request
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 // CreateItemRequest represents the item creation request
 type CreateItemRequest struct {
-	Name   string `json:"name" binding:"required"`
-	Status string `json:"status"`
+	Name        string `json:"name" binding:"required"`
+	Stock       int    `json:"stock" binding:"min=0"`
+	Price       *Money `json:"price" binding:"required"` // currency defaults to STORE_CURRENCY
+	MaxPerOrder int    `json:"max_per_order" binding:"min=0"`
 }
 
 // AddToCartRequest represents the add to cart request
@@ -102,7 +648,30 @@ type AddToCartRequest struct {
 	ItemIDs []uint `json:"item_ids" binding:"required"`
 }
 
+// AdjustStockRequest represents a manual stock adjustment
+type AdjustStockRequest struct {
+	Delta  int    `json:"delta" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+// UpdateCartItemRequest represents the cart quantity update request; zero removes the item
+type UpdateCartItemRequest struct {
+	Quantity *int `json:"quantity" binding:"required,min=0"`
+}
+
 // CreateOrderRequest represents the order creation request
 type CreateOrderRequest struct {
 	CartID uint `json:"cart_id" binding:"required"`
+}
+
+// CancelOrderRequest explains why a customer is cancelling their order
+type CancelOrderRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// UpdateOrderStatusRequest moves an order to a new status
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"max=500"`
 } 
\ No newline at end of file
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrMoneyOverflow is returned when an amount does not fit in 64 bits
+	ErrMoneyOverflow = errors.New("money amount overflow")
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// currencyExponents lists ISO 4217 currencies whose minor unit is not 1/100
+var currencyExponents = map[string]int{
+	"BHD": 3, "CLP": 0, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "OMR": 3, "TND": 3, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+}
+
+// Money is an amount in the currency's minor unit (e.g. cents) with its ISO 4217 code.
+// Arithmetic is integer-only so totals never suffer floating-point rounding.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 currency code
+func ValidCurrency(code string) bool {
+	return currencyPattern.MatchString(code)
+}
+
+// Add returns m + other; both must share a currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Multiply returns m scaled by a non-negative quantity
+func (m Money) Multiply(quantity int64) (Money, error) {
+	if quantity < 0 {
+		return Money{}, errors.New("quantity must not be negative")
+	}
+	if quantity != 0 && (m.Amount > math.MaxInt64/quantity || m.Amount < math.MinInt64/quantity) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}, nil
+}
+
+// String formats the amount in major units, e.g. "19.99 USD"
+func (m Money) String() string {
+	exponent, ok := currencyExponents[m.Currency]
+	if !ok {
+		exponent = 2
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if exponent == 0 {
+		return fmt.Sprintf("%s%d %s", sign, amount, m.Currency)
+	}
+
+	digits := fmt.Sprintf("%0*d", exponent+1, amount)
+	split := len(digits) - exponent
+	return strings.TrimSpace(fmt.Sprintf("%s%s.%s %s", sign, digits[:split], digits[split:], m.Currency))
+}
@@ -0,0 +1,269 @@
+package models
+
+import (
+	"time"
+)
+
+// API views are the shapes returned to clients. Handlers build them from the
+// persistence models so credentials, security state and ORM relations never
+// leak into responses.
+
+// UserView is the public representation of a user account
+type UserView struct {
+	ID            uint            `json:"id"`
+	Username      string          `json:"username"`
+	DisplayName   string          `json:"display_name"`
+	Role          string          `json:"role"`
+	Email         string          `json:"email"`
+	EmailVerified bool            `json:"email_verified"`
+	Phone         string          `json:"phone"`
+	Preferences   UserPreferences `json:"preferences"`
+	TOTPEnabled   bool            `json:"totp_enabled"`
+	CartID        *uint           `json:"cart_id"`
+	LockedUntil   *time.Time      `json:"locked_until,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// ProfileView is the signed-in user's own profile with summaries of their cart and orders
+type ProfileView struct {
+	UserView
+	Cart   *CartSummary `json:"cart"`
+	Orders OrderSummary `json:"orders"`
+}
+
+// CartSummary summarizes the user's current cart
+type CartSummary struct {
+	ID        uint   `json:"id"`
+	Status    string `json:"status"`
+	ItemCount int    `json:"item_count"`
+}
+
+// OrderSummary summarizes the user's order history
+type OrderSummary struct {
+	Count       int        `json:"count"`
+	LastOrderAt *time.Time `json:"last_order_at,omitempty"`
+}
+
+// UserRef identifies the owner of a cart or order in admin listings
+type UserRef struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// ItemView is the public representation of a store item
+type ItemView struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	Price       Money     `json:"price"`
+	Stock       int       `json:"stock"`
+	Available   int       `json:"available"`
+	MaxPerOrder int       `json:"max_per_order,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CartLineView is an item in a cart with its quantity and line total
+type CartLineView struct {
+	ItemView
+	Quantity      int        `json:"quantity"`
+	LineTotal     Money      `json:"line_total"`
+	ReservedUntil *time.Time `json:"reserved_until"`
+}
+
+// CartView is the public representation of a cart, its lines and their subtotal
+type CartView struct {
+	ID        uint           `json:"id"`
+	UserID    uint           `json:"user_id"`
+	Owner     *UserRef       `json:"owner,omitempty"`
+	Name      string         `json:"name"`
+	Status    string         `json:"status"`
+	Items     []CartLineView `json:"items"`
+	Subtotal  Money          `json:"subtotal"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// OrderLineView is an item as it was purchased
+type OrderLineView struct {
+	ItemID    uint   `json:"item_id"`
+	Name      string `json:"name"`
+	UnitPrice Money  `json:"unit_price"`
+	Quantity  int    `json:"quantity"`
+	LineTotal Money  `json:"line_total"`
+}
+
+// OrderView is the public representation of an order and its purchased lines
+type OrderView struct {
+	ID              uint               `json:"id"`
+	UserID          uint               `json:"user_id"`
+	Owner           *UserRef           `json:"owner,omitempty"`
+	CartID          uint               `json:"cart_id"`
+	Items           []OrderLineView    `json:"items"`
+	Total           Money              `json:"total"`
+	Status          string             `json:"status"`
+	History         []OrderStatusEvent `json:"history,omitempty"`
+	CancelReason    string             `json:"cancel_reason,omitempty"`
+	RefundReference string             `json:"refund_reference,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// NewUserView builds the view of a user
+func NewUserView(user *User) UserView {
+	return UserView{
+		ID:            user.ID,
+		Username:      user.Username,
+		DisplayName:   user.DisplayName,
+		Role:          user.Role,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Phone:         user.Phone,
+		Preferences:   user.Preferences,
+		TOTPEnabled:   user.TOTPEnabled,
+		CartID:        user.CartID,
+		LockedUntil:   user.LockedUntil,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+}
+
+// NewUserViews builds views of a list of users
+func NewUserViews(users []User) []UserView {
+	views := make([]UserView, 0, len(users))
+	for i := range users {
+		views = append(views, NewUserView(&users[i]))
+	}
+	return views
+}
+
+// newUserRef returns a reference to user, or nil if the relation was not loaded
+func newUserRef(user *User) *UserRef {
+	if user == nil {
+		return nil
+	}
+	return &UserRef{ID: user.ID, Username: user.Username}
+}
+
+// NewItemView builds the view of an item
+func NewItemView(item *Item) ItemView {
+	return ItemView{
+		ID:          item.ID,
+		Name:        item.Name,
+		Status:      item.Status,
+		Price:       item.Price,
+		Stock:       item.Stock,
+		Available:   item.Stock - item.Reserved,
+		MaxPerOrder: item.MaxPerOrder,
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+	}
+}
+
+// NewItemViews builds views of a list of items
+func NewItemViews(items []Item) []ItemView {
+	views := make([]ItemView, 0, len(items))
+	for i := range items {
+		views = append(views, NewItemView(&items[i]))
+	}
+	return views
+}
+
+// NewCartView builds the view of a cart from its CartItems (with Item preloaded) and, if loaded,
+// its User and Reservations.
+// The subtotal of an empty cart is zero in currency.
+func NewCartView(cart *Cart, currency string) (CartView, error) {
+	view := CartView{
+		ID:        cart.ID,
+		UserID:    cart.UserID,
+		Owner:     newUserRef(cart.User),
+		Name:      cart.Name,
+		Status:    cart.Status,
+		Items:     make([]CartLineView, 0, len(cart.CartItems)),
+		Subtotal:  Money{Currency: currency},
+		CreatedAt: cart.CreatedAt,
+		UpdatedAt: cart.UpdatedAt,
+	}
+
+	now := time.Now()
+	for _, cartItem := range cart.CartItems {
+		if cartItem.Item == nil {
+			continue
+		}
+		lineTotal, err := cartItem.Item.Price.Multiply(int64(cartItem.Quantity))
+		if err != nil {
+			return CartView{}, err
+		}
+		if len(view.Items) == 0 {
+			view.Subtotal.Currency = lineTotal.Currency
+		}
+		if view.Subtotal, err = view.Subtotal.Add(lineTotal); err != nil {
+			return CartView{}, err
+		}
+		line := CartLineView{
+			ItemView:  NewItemView(cartItem.Item),
+			Quantity:  cartItem.Quantity,
+			LineTotal: lineTotal,
+		}
+		// A hold past its expiry is no longer held, even before the sweeper releases it
+		for i := range cart.Reservations {
+			if cart.Reservations[i].ItemID == cartItem.ItemID && cart.Reservations[i].ExpiresAt.After(now) {
+				line.ReservedUntil = &cart.Reservations[i].ExpiresAt
+			}
+		}
+		view.Items = append(view.Items, line)
+	}
+
+	return view, nil
+}
+
+// NewCartViews builds views of a list of carts
+func NewCartViews(carts []Cart, currency string) ([]CartView, error) {
+	views := make([]CartView, 0, len(carts))
+	for i := range carts {
+		view, err := NewCartView(&carts[i], currency)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// NewOrderView builds the view of an order from its loaded Lines and, if preloaded, its User and StatusEvents
+func NewOrderView(order *Order) OrderView {
+	lines := make([]OrderLineView, 0, len(order.Lines))
+	for _, line := range order.Lines {
+		lines = append(lines, OrderLineView{
+			ItemID:    line.ItemID,
+			Name:      line.Name,
+			UnitPrice: line.UnitPrice,
+			Quantity:  line.Quantity,
+			LineTotal: line.LineTotal,
+		})
+	}
+	return OrderView{
+		ID:              order.ID,
+		UserID:          order.UserID,
+		Owner:           newUserRef(order.User),
+		CartID:          order.CartID,
+		Items:           lines,
+		Total:           order.Total,
+		Status:          order.Status,
+		History:         order.StatusEvents,
+		CancelReason:    order.CancelReason,
+		RefundReference: order.RefundReference,
+		CreatedAt:       order.CreatedAt,
+		UpdatedAt:       order.UpdatedAt,
+	}
+}
+
+// NewOrderViews builds views of a list of orders
+func NewOrderViews(orders []Order) []OrderView {
+	views := make([]OrderView, 0, len(orders))
+	for i := range orders {
+		views = append(views, NewOrderView(&orders[i]))
+	}
+	return views
+}
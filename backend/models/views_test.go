@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewCartViewOmitsExpiredHolds(t *testing.T) {
+	now := time.Now()
+	item := func(id uint) *Item { return &Item{ID: id, Name: "item", Price: Money{Amount: 100, Currency: "USD"}} }
+	cart := Cart{
+		CartItems: []CartItem{
+			{ItemID: 1, Item: item(1), Quantity: 1},
+			{ItemID: 2, Item: item(2), Quantity: 1},
+		},
+		Reservations: []StockReservation{
+			{ItemID: 1, Quantity: 1, ExpiresAt: now.Add(time.Minute)},
+			{ItemID: 2, Quantity: 1, ExpiresAt: now.Add(-time.Minute)},
+		},
+	}
+
+	view, err := NewCartView(&cart, "USD")
+	if err != nil {
+		t.Fatalf("NewCartView: %v", err)
+	}
+	if view.Items[0].ReservedUntil == nil {
+		t.Error("active hold not reported")
+	}
+	if view.Items[1].ReservedUntil != nil {
+		t.Errorf("expired hold reported until %v", view.Items[1].ReservedUntil)
+	}
+}
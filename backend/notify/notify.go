@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"ecommerce-backend/config"
+)
+
+// Message represents a notification addressed to a user
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// LogNotifier writes messages to the application log
+type LogNotifier struct{}
+
+// Send logs the message
+func (LogNotifier) Send(msg Message) error {
+	log.Printf("Notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileNotifier appends messages to a file, useful for local development
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Send appends the message to the notifier's file
+func (f *FileNotifier) Send(msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "--- %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+// Current is the notifier used by the application
+var Current Notifier = LogNotifier{}
+
+// InitNotifier selects the notifier configured by NOTIFY_DRIVER
+func InitNotifier() {
+	switch config.AppConfig.Notify.Driver {
+	case "file":
+		Current = &FileNotifier{Path: config.AppConfig.Notify.FilePath}
+	case "smtp":
+		cfg := config.AppConfig.Notify
+		Current = &SMTPNotifier{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}
+	case "log", "":
+		Current = LogNotifier{}
+	default:
+		log.Printf("Unknown notify driver %q, falling back to log", config.AppConfig.Notify.Driver)
+		Current = LogNotifier{}
+	}
+}
+
+// Send delivers a message through the current notifier
+func Send(msg Message) error {
+	return Current.Send(msg)
+}
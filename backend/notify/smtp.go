@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier sends messages as plain-text email through an SMTP server.
+// Leaving Username empty disables authentication, which suits local mail catchers.
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the message to msg.To, which must be an email address
+func (s *SMTPNotifier) Send(msg Message) error {
+	if !strings.Contains(msg.To, "@") {
+		return fmt.Errorf("recipient %q is not an email address", msg.To)
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	body := strings.Join([]string{
+		"From: " + s.From,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	return smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, s.From, []string{msg.To}, []byte(body))
+}
@@ -0,0 +1,56 @@
+package payment
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ecommerce-backend/models"
+)
+
+// Gateway issues refunds for payments captured on orders
+type Gateway interface {
+	// Refund returns amount to the customer who paid for the order and returns the provider's reference.
+	// A refund repeated with the same idempotency key must return the first refund's reference without paying again.
+	Refund(idempotencyKey string, orderID uint, amount models.Money, reason string) (string, error)
+}
+
+// LogGateway records refunds in the application log; it stands in until a payment provider is integrated
+type LogGateway struct {
+	mu     sync.Mutex
+	issued map[string]string
+}
+
+// Refund logs the refund and returns a local reference for it, or the reference already issued for key
+func (g *LogGateway) Refund(idempotencyKey string, orderID uint, amount models.Money, reason string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if reference, ok := g.issued[idempotencyKey]; ok {
+		log.Printf("Refund %s for order %d was already issued", reference, orderID)
+		return reference, nil
+	}
+	if g.issued == nil {
+		g.issued = make(map[string]string)
+	}
+
+	reference := fmt.Sprintf("refund_%d_%d", orderID, time.Now().UnixNano())
+	g.issued[idempotencyKey] = reference
+	log.Printf("Refund %s of %s for order %d: %s", reference, amount, orderID, reason)
+	return reference, nil
+}
+
+// Current is the payment gateway used by the application
+var Current Gateway = &LogGateway{}
+
+// RefundKey is the idempotency key of the refund of an order. An order is refunded at most once,
+// so a retry after the gateway succeeded but the order failed to save returns the original refund.
+func RefundKey(orderID uint) string {
+	return fmt.Sprintf("order_%d_refund", orderID)
+}
+
+// Refund issues the order's refund through the current gateway
+func Refund(orderID uint, amount models.Money, reason string) (string, error) {
+	return Current.Refund(RefundKey(orderID), orderID, amount, reason)
+}
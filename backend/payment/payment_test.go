@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"testing"
+
+	"ecommerce-backend/models"
+)
+
+// countingGateway wraps a gateway and counts the refunds it actually pays out
+type countingGateway struct {
+	LogGateway
+	paid int
+}
+
+func (g *countingGateway) Refund(idempotencyKey string, orderID uint, amount models.Money, reason string) (string, error) {
+	if _, ok := g.issued[idempotencyKey]; !ok {
+		g.paid++
+	}
+	return g.LogGateway.Refund(idempotencyKey, orderID, amount, reason)
+}
+
+func TestRefundRetryReturnsOriginalRefund(t *testing.T) {
+	gateway := &countingGateway{}
+	Current = gateway
+	defer func() { Current = &LogGateway{} }()
+	amount := models.Money{Amount: 1999, Currency: "USD"}
+
+	first, err := Refund(7, amount, "retry")
+	if err != nil {
+		t.Fatalf("first refund: %v", err)
+	}
+	second, err := Refund(7, amount, "retry")
+	if err != nil {
+		t.Fatalf("second refund: %v", err)
+	}
+	if first != second {
+		t.Errorf("retry reference = %q, want %q", second, first)
+	}
+	if other, _ := Refund(8, amount, "other order"); other == first {
+		t.Errorf("order 8 reused order 7's refund %q", first)
+	}
+	if gateway.paid != 2 {
+		t.Errorf("paid %d refunds, want 2", gateway.paid)
+	}
+}
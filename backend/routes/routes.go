@@ -4,6 +4,7 @@ import (
 	"ecommerce-backend/config"
 	"ecommerce-backend/handlers"
 	"ecommerce-backend/middleware"
+	"ecommerce-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,8 +16,8 @@ func SetupRoutes() *gin.Engine {
 	// Enable CORS
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", config.AppConfig.CORS.Origin)
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -28,32 +29,94 @@ func SetupRoutes() *gin.Engine {
 
 	// User routes
 	r.POST("/users", handlers.CreateUser)
-	r.GET("/users", handlers.ListUsers)
+	r.GET("/users", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermUsersRead), handlers.ListUsers)
 	r.POST("/users/login", handlers.Login)
+	r.POST("/users/login/2fa", handlers.LoginTwoFactor)
+	r.POST("/users/token/refresh", handlers.RefreshToken)
+	r.POST("/users/logout", middleware.AuthMiddleware(), middleware.RequireSession(), handlers.Logout)
+	r.POST("/users/password/forgot", handlers.ForgotPassword)
+	r.POST("/users/password/reset", handlers.ResetPassword)
+	r.POST("/users/email/verify", handlers.VerifyEmail)
+	r.POST("/users/:id/logout", middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequirePermission(models.PermUsersWrite), handlers.ForceLogout)
+	r.GET("/users/:id/login-attempts", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermUsersRead), handlers.ListLoginAttempts)
+	r.POST("/users/:id/unlock", middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequirePermission(models.PermUsersWrite), handlers.UnlockUser)
+	r.GET("/users/:id/api-keys", middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequirePermission(models.PermUsersRead), handlers.ListUserAPIKeys)
+	r.POST("/users/:id/api-keys", middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequirePermission(models.PermUsersWrite), handlers.CreateUserAPIKey)
+	r.DELETE("/users/:id/api-keys/:keyId", middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequirePermission(models.PermUsersWrite), handlers.RevokeUserAPIKey)
+	r.PUT("/users/:id/role", middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequirePermission(models.PermRolesManage), handlers.UpdateUserRole)
+
+	// Role management routes (protected; like account management, not available to API keys)
+	roleRoutes := r.Group("/roles")
+	roleRoutes.Use(middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequirePermission(models.PermRolesManage))
+	{
+		roleRoutes.GET("", handlers.ListRoles)
+		roleRoutes.POST("", handlers.CreateRole)
+		roleRoutes.PUT("/:name", handlers.UpdateRole)
+		roleRoutes.DELETE("/:name", handlers.DeleteRole)
+	}
+	r.GET("/permissions", middleware.AuthMiddleware(), middleware.RequireSession(), middleware.RequirePermission(models.PermRolesManage), handlers.ListPermissions)
+
+	// External identity provider routes
+	r.GET("/auth/providers", handlers.ListProviders)
+	r.GET("/auth/:provider/login", handlers.ProviderLogin)
+	r.GET("/auth/:provider/callback", handlers.ProviderCallback)
+
+	// Current user routes (protected; API keys cannot manage the account)
+	meRoutes := r.Group("/users/me")
+	meRoutes.Use(middleware.AuthMiddleware(), middleware.RequireSession())
+	{
+		meRoutes.GET("", handlers.GetProfile)
+		meRoutes.PATCH("", handlers.UpdateProfile)
+		meRoutes.PUT("/password", handlers.ChangePassword)
+		meRoutes.POST("/email/resend", handlers.ResendVerification)
+		meRoutes.POST("/2fa/enroll", handlers.EnrollTwoFactor)
+		meRoutes.POST("/2fa/confirm", handlers.ConfirmTwoFactor)
+		meRoutes.DELETE("/2fa", handlers.DisableTwoFactor)
+		meRoutes.GET("/addresses", handlers.ListAddresses)
+		meRoutes.POST("/addresses", handlers.CreateAddress)
+		meRoutes.GET("/addresses/:id", handlers.GetAddress)
+		meRoutes.PUT("/addresses/:id", handlers.UpdateAddress)
+		meRoutes.DELETE("/addresses/:id", handlers.DeleteAddress)
+		meRoutes.GET("/identities", handlers.ListIdentities)
+		meRoutes.POST("/identities/:provider", handlers.LinkIdentity)
+		meRoutes.POST("/identities/:provider/complete", handlers.CompleteLinkIdentity)
+		meRoutes.DELETE("/identities/:id", handlers.UnlinkIdentity)
+		meRoutes.GET("/sessions", handlers.ListSessions)
+		meRoutes.DELETE("/sessions/others", handlers.RevokeOtherSessions)
+		meRoutes.DELETE("/sessions/:id", handlers.RevokeSession)
+		meRoutes.GET("/api-keys", handlers.ListAPIKeys)
+		meRoutes.POST("/api-keys", handlers.CreateAPIKey)
+		meRoutes.DELETE("/api-keys/:id", handlers.RevokeAPIKey)
+	}
 
 	// Item routes
-	r.POST("/items", handlers.CreateItem)
+	r.POST("/items", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermItemsWrite), handlers.CreateItem)
 	r.GET("/items", handlers.ListItems)
+	r.GET("/items/:id/stock", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermItemsWrite), handlers.ListStockAdjustments)
+	r.POST("/items/:id/stock", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermItemsWrite), handlers.AdjustItemStock)
 
 	// Cart routes (protected)
 	cartRoutes := r.Group("/carts")
 	cartRoutes.Use(middleware.AuthMiddleware())
 	{
-		cartRoutes.POST("/", handlers.AddToCart)
-		cartRoutes.GET("/my", handlers.GetUserCart)
-		cartRoutes.DELETE("/clear", handlers.ClearCart)
-		cartRoutes.DELETE("/remove", handlers.RemoveFromCart)
+		cartRoutes.POST("/", middleware.RequireScope(models.ScopeCartWrite), handlers.AddToCart)
+		cartRoutes.GET("/my", middleware.RequireScope(models.ScopeCartRead), handlers.GetUserCart)
+		cartRoutes.PUT("/items/:id", middleware.RequireScope(models.ScopeCartWrite), handlers.UpdateCartItem)
+		cartRoutes.DELETE("/clear", middleware.RequireScope(models.ScopeCartWrite), handlers.ClearCart)
+		cartRoutes.DELETE("/remove", middleware.RequireScope(models.ScopeCartWrite), handlers.RemoveFromCart)
 	}
-	r.GET("/carts", handlers.ListCarts) // Public endpoint
+	r.GET("/carts", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermCartsRead), handlers.ListCarts)
 
 	// Order routes (protected)
 	orderRoutes := r.Group("/orders")
 	orderRoutes.Use(middleware.AuthMiddleware())
 	{
-		orderRoutes.POST("/", handlers.CreateOrder)
-		orderRoutes.GET("/my", handlers.GetUserOrders)
+		orderRoutes.POST("/", middleware.RequireScope(models.ScopeOrdersPlace), handlers.CreateOrder)
+		orderRoutes.GET("/my", middleware.RequireScope(models.ScopeOrdersHistory), handlers.GetUserOrders)
+		orderRoutes.POST("/:id/cancel", middleware.RequireScope(models.ScopeOrdersPlace), handlers.CancelOrder)
 	}
-	r.GET("/orders", handlers.ListOrders) // Public endpoint
+	r.GET("/orders", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermOrdersRead), handlers.ListOrders)
+	r.PATCH("/orders/:id/status", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermOrdersWrite), handlers.UpdateOrderStatus)
 
 	return r
 } 
\ No newline at end of file
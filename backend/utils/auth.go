@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -25,4 +26,26 @@ func GenerateToken() (string, error) {
 		return "", err
 	}
 	return hex.EncodeToString(bytes), nil
-} 
\ No newline at end of file
+}
+
+// HashToken returns the SHA-256 hex digest of a token for storage
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyPrefixLength is the number of random hex characters in an API key prefix
+const apiKeyPrefixLength = 12
+
+// GenerateAPIKey returns a new API key as its public prefix and secret; the key is "<prefix>.<secret>"
+func GenerateAPIKey() (string, string, error) {
+	random, err := GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+	return "ek_" + random[:apiKeyPrefixLength], secret, nil
+}
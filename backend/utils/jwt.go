@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"ecommerce-backend/config"
+)
+
+var (
+	// ErrTokenMalformed is returned when a token cannot be decoded
+	ErrTokenMalformed = errors.New("token is malformed")
+	// ErrTokenSignature is returned when a token's signature does not match
+	ErrTokenSignature = errors.New("token signature is invalid")
+	// ErrTokenExpired is returned when a token's exp claim is in the past
+	ErrTokenExpired = errors.New("token has expired")
+)
+
+// Claims represents the payload of an access token
+type Claims struct {
+	ID        string `json:"jti"`
+	UserID    uint   `json:"uid"`
+	SessionID uint   `json:"sid"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+var jwtEncoding = base64.RawURLEncoding
+
+// GenerateJWT issues an HS256 signed access token for the given user session
+func GenerateJWT(userID, sessionID uint, username, role string, expiresAt time.Time) (string, error) {
+	jti, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := Claims{
+		ID:        jti,
+		UserID:    userID,
+		SessionID: sessionID,
+		Username:  username,
+		Role:      role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := jwtEncoding.EncodeToString(header) + "." + jwtEncoding.EncodeToString(payload)
+	token := signingInput + "." + jwtEncoding.EncodeToString(signJWT(signingInput))
+	return token, nil
+}
+
+// ParseJWT verifies the token signature and expiry and returns its claims
+func ParseJWT(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	headerBytes, err := jwtEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if header.Alg != "HS256" {
+		return nil, ErrTokenSignature
+	}
+
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !hmac.Equal(signature, signJWT(parts[0]+"."+parts[1])) {
+		return nil, ErrTokenSignature
+	}
+
+	payload, err := jwtEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+// signJWT computes the HMAC-SHA256 signature using the configured secret
+func signJWT(signingInput string) []byte {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWT.Secret))
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"ecommerce-backend/config"
+)
+
+// passwordDenylist holds common passwords that are always rejected, lowercased
+var passwordDenylist = map[string]bool{}
+
+// LoadPasswordDenylist reads one password per line from path; blank lines and # comments are ignored
+func LoadPasswordDenylist(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	denylist := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denylist[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	passwordDenylist = denylist
+	return nil
+}
+
+// ValidatePassword checks a new password against the configured password policy
+func ValidatePassword(password, username string) error {
+	policy := config.AppConfig.Auth
+
+	if len([]rune(password)) < policy.PasswordMinLength {
+		return fmt.Errorf("password must be at least %d characters long", policy.PasswordMinLength)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < policy.PasswordMinClasses {
+		return fmt.Errorf("password must contain at least %d of: lowercase letters, uppercase letters, digits, symbols", policy.PasswordMinClasses)
+	}
+
+	if passwordDenylist[strings.ToLower(password)] {
+		return fmt.Errorf("password is too common")
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return fmt.Errorf("password must not match the username")
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// postalCodeRules maps ISO 3166-1 alpha-2 country codes to their postal code format.
+// Codes are upper-cased and trimmed before matching.
+var postalCodeRules = map[string]*regexp.Regexp{
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"CA": regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] ?\d[ABCEGHJ-NPRSTV-Z]\d$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"NZ": regexp.MustCompile(`^\d{4}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"SG": regexp.MustCompile(`^\d{6}$`),
+}
+
+// noPostalCodeCountries do not use postal codes, so an empty code is accepted
+var noPostalCodeCountries = map[string]bool{
+	"AE": true,
+	"HK": true,
+	"IE": true, // Eircodes are optional
+	"QA": true,
+}
+
+var (
+	countryCodePattern    = regexp.MustCompile(`^[A-Z]{2}$`)
+	genericPostalPattern  = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,14}[A-Z0-9]$`)
+	errCountryCodeInvalid = errors.New("country must be an ISO 3166-1 alpha-2 code, e.g. US")
+)
+
+// NormalizeCountryCode upper-cases and validates an ISO 3166-1 alpha-2 country code
+func NormalizeCountryCode(country string) (string, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if !countryCodePattern.MatchString(country) {
+		return "", errCountryCodeInvalid
+	}
+	return country, nil
+}
+
+// ValidatePostalCode checks a postal code against the rules for country and returns it normalized.
+// Countries without a specific rule accept any plausible alphanumeric code.
+func ValidatePostalCode(country, code string) (string, error) {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), " "))
+
+	if code == "" {
+		if noPostalCodeCountries[country] {
+			return "", nil
+		}
+		return "", errors.New("postal_code is required")
+	}
+
+	rule, ok := postalCodeRules[country]
+	if !ok {
+		rule = genericPostalPattern
+	}
+	if !rule.MatchString(code) {
+		return "", fmt.Errorf("postal_code %q is not valid for %s", code, country)
+	}
+	return code, nil
+}
@@ -1,4 +1,5 @@
 import React from 'react'
+import { formatMoney } from '../utils/money'
 
 function CartModal({ cart, isOpen, onClose, onCheckout, onRemoveItem, onClearCart }) {
   if (!isOpen) return null
@@ -16,7 +17,8 @@ function CartModal({ cart, isOpen, onClose, onCheckout, onRemoveItem, onClearCar
             <>
               <div className="cart-summary">
                 <p><strong>Cart ID:</strong> {cart.id}</p>
-                <p><strong>Total Items:</strong> {cart.items.length}</p>
+                <p><strong>Total Items:</strong> {cart.items.reduce((sum, item) => sum + (item.quantity || 1), 0)}</p>
+                <p><strong>Subtotal:</strong> {formatMoney(cart.subtotal)}</p>
               </div>
               
               <div className="cart-items">
@@ -27,6 +29,8 @@ function CartModal({ cart, isOpen, onClose, onCheckout, onRemoveItem, onClearCar
                       <h4>{item.name}</h4>
                       <p>Item ID: {item.id}</p>
                       <p>Status: {item.status}</p>
+                      <p>Quantity: {item.quantity}</p>
+                      <p>Price: {formatMoney(item.line_total)}</p>
                     </div>
                   </div>
                 ))}
@@ -1,10 +1,13 @@
 import React, { useState } from 'react'
 import { createItem } from '../services/api'
+import { toMinorUnits } from '../utils/money'
 
 function CreateItemModal({ isOpen, onClose, onItemCreated }) {
   const [formData, setFormData] = useState({
     name: '',
-    status: 'available'
+    stock: '0',
+    price: '',
+    currency: 'USD'
   })
   const [loading, setLoading] = useState(false)
 
@@ -17,12 +20,23 @@ function CreateItemModal({ isOpen, onClose, onItemCreated }) {
 
   const handleSubmit = async (e) => {
     e.preventDefault()
+
+    const amount = toMinorUnits(formData.price, formData.currency)
+    if (amount === null) {
+      showToast('Please enter a valid price', 'error')
+      return
+    }
+
     setLoading(true)
 
     try {
-      await createItem(formData)
+      await createItem({
+        name: formData.name,
+        stock: parseInt(formData.stock, 10) || 0,
+        price: { amount, currency: formData.currency }
+      })
       showToast('Item created successfully!', 'success')
-      setFormData({ name: '', status: 'available' })
+      setFormData({ name: '', stock: '0', price: '', currency: 'USD' })
       onItemCreated()
       onClose()
     } catch (error) {
@@ -67,18 +81,34 @@ function CreateItemModal({ isOpen, onClose, onItemCreated }) {
             </div>
             
             <div className="form-group">
-              <label htmlFor="status">
-                Status
+              <label htmlFor="price">
+                Price ({formData.currency})
               </label>
-              <select
-                id="status"
-                name="status"
-                value={formData.status}
+              <input
+                type="text"
+                inputMode="decimal"
+                id="price"
+                name="price"
+                value={formData.price}
                 onChange={handleInputChange}
-              >
-                <option value="available">Available</option>
-                <option value="out_of_stock">Out of Stock</option>
-              </select>
+                required
+                placeholder="0.00"
+              />
+            </div>
+            
+            <div className="form-group">
+              <label htmlFor="stock">
+                Opening Stock
+              </label>
+              <input
+                type="number"
+                id="stock"
+                name="stock"
+                min="0"
+                step="1"
+                value={formData.stock}
+                onChange={handleInputChange}
+              />
             </div>
             
             <div className="modal-footer">
@@ -6,11 +6,15 @@ import {
   clearCart,
   removeFromCart,
   createOrder, 
-  getUserOrders 
+  cancelOrder,
+  getUserOrders,
+  verifyEmail,
+  resendVerification
 } from '../services/api'
 import CartModal from './CartModal'
 import CreateItemModal from './CreateItemModal'
 import OrderHistoryModal from './OrderHistoryModal'
+import { formatMoney } from '../utils/money'
 
 function ItemsScreen({ user, onLogout, showToast }) {
   const [items, setItems] = useState([])
@@ -90,10 +94,31 @@ function ItemsScreen({ user, onLogout, showToast }) {
       fetchOrders() // Refresh orders data
     } catch (error) {
       console.error('Checkout error:', error)
+      if (error.code === 'email_unverified') {
+        await handleVerifyEmail()
+        return
+      }
       showToast(`Failed to place order: ${error.message}`, 'error')
     }
   }
 
+  const handleVerifyEmail = async () => {
+    // A verification email may already be on its way, so a throttled resend is fine
+    await resendVerification().catch(() => {})
+    const token = window.prompt('Verify your email address before placing orders. Paste the token from the verification email:')
+    if (!token) {
+      showToast('Verify your email address to place orders', 'error')
+      return
+    }
+
+    try {
+      await verifyEmail(token.trim())
+      showToast('Email verified! You can now place your order.', 'success')
+    } catch (error) {
+      showToast(`Failed to verify email: ${error.message}`, 'error')
+    }
+  }
+
   const handleViewCart = () => {
     console.log('Cart data:', cart)
     setIsCartModalOpen(true)
@@ -144,6 +169,20 @@ function ItemsScreen({ user, onLogout, showToast }) {
     }
   }
 
+  const handleCancelOrder = async (orderId) => {
+    const reason = window.prompt('Why are you cancelling this order?')
+    if (!reason) return
+
+    try {
+      await cancelOrder(orderId, reason)
+      showToast('Order cancelled successfully!', 'success')
+      fetchOrders() // Refresh orders data
+      fetchItems() // Stock is back on the shelf
+    } catch (error) {
+      showToast(`Failed to cancel order: ${error.message}`, 'error')
+    }
+  }
+
   const handleViewOrders = () => {
     console.log('Orders data:', orders)
     setIsOrderHistoryModalOpen(true)
@@ -191,6 +230,7 @@ function ItemsScreen({ user, onLogout, showToast }) {
           {items.map((item) => (
             <div key={item.id} className="item-card">
               <h3>{item.name}</h3>
+              <p className="item-price">{formatMoney(item.price)}</p>
               <p>Status: {item.status}</p>
               <button 
                 onClick={() => handleAddToCart(item.id)}
@@ -250,6 +290,7 @@ function ItemsScreen({ user, onLogout, showToast }) {
           orders={orders}
           isOpen={isOrderHistoryModalOpen}
           onClose={() => setIsOrderHistoryModalOpen(false)}
+          onCancelOrder={handleCancelOrder}
         />
       </div>
     </div>
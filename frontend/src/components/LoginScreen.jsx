@@ -1,13 +1,18 @@
 import React, { useState } from 'react'
-import { loginUser, createUser } from '../services/api'
+import { loginUser, loginTwoFactor, createUser, verifyEmail } from '../services/api'
 
 function LoginScreen({ onLogin, showToast }) {
   const [isSignup, setIsSignup] = useState(false)
   const [formData, setFormData] = useState({
     username: '',
+    email: '',
     password: ''
   })
   const [loading, setLoading] = useState(false)
+  const [challengeToken, setChallengeToken] = useState(null)
+  const [twoFactorCode, setTwoFactorCode] = useState('')
+  const [isVerifying, setIsVerifying] = useState(false)
+  const [verificationToken, setVerificationToken] = useState('')
 
   const handleInputChange = (e) => {
     setFormData({
@@ -24,12 +29,21 @@ function LoginScreen({ onLogin, showToast }) {
       if (isSignup) {
         // Create new user
         const response = await createUser(formData)
-        showToast('User created successfully! Please login.', 'success')
+        showToast('User created successfully! Check your email for your verification token.', 'success')
         setIsSignup(false)
-        setFormData({ username: '', password: '' })
+        setIsVerifying(true)
+        setFormData({ username: '', email: '', password: '' })
       } else {
         // Login existing user
-        const response = await loginUser(formData)
+        const response = await loginUser({
+          username: formData.username,
+          password: formData.password
+        })
+        if (response.challenge_token) {
+          // Two-factor accounts get a session only after the second step
+          setChallengeToken(response.challenge_token)
+          return
+        }
         onLogin(response.user, response.token)
       }
     } catch (error) {
@@ -39,6 +53,158 @@ function LoginScreen({ onLogin, showToast }) {
     }
   }
 
+  const handleTwoFactorSubmit = async (e) => {
+    e.preventDefault()
+    setLoading(true)
+
+    try {
+      const response = await loginTwoFactor(challengeToken, twoFactorCode.trim())
+      onLogin(response.user, response.token)
+    } catch (error) {
+      showToast(error.message || 'An error occurred', 'error')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const handleCancelTwoFactor = () => {
+    setChallengeToken(null)
+    setTwoFactorCode('')
+  }
+
+  const handleVerifySubmit = async (e) => {
+    e.preventDefault()
+    setLoading(true)
+
+    try {
+      await verifyEmail(verificationToken.trim())
+      showToast('Email verified! You can now sign in.', 'success')
+      setIsVerifying(false)
+      setVerificationToken('')
+    } catch (error) {
+      showToast(error.message || 'An error occurred', 'error')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  if (isVerifying) {
+    return (
+      <div className="login-page">
+        <div className="login-background">
+          <div className="login-container">
+            <div className="login-header">
+              <div className="logo">
+                <h1>E-Commerce</h1>
+              </div>
+              <p className="login-subtitle">Paste the verification token from the email we sent you.</p>
+            </div>
+
+            <form onSubmit={handleVerifySubmit} className="login-form">
+              <div className="form-group">
+                <label htmlFor="verificationToken">
+                  Verification token
+                </label>
+                <input
+                  type="text"
+                  id="verificationToken"
+                  name="verificationToken"
+                  value={verificationToken}
+                  onChange={(e) => setVerificationToken(e.target.value)}
+                  placeholder="Enter your verification token"
+                  autoFocus
+                  required
+                />
+              </div>
+
+              <button
+                type="submit"
+                className="btn btn-primary login-btn"
+                disabled={loading}
+              >
+                {loading ? (
+                  <>
+                    <span className="loading-spinner"></span>
+                    Loading...
+                  </>
+                ) : 'Verify Email'}
+              </button>
+            </form>
+
+            <div className="login-footer">
+              <button
+                type="button"
+                className="btn btn-secondary switch-btn"
+                onClick={() => setIsVerifying(false)}
+              >
+                ← Back to Login
+              </button>
+            </div>
+          </div>
+        </div>
+      </div>
+    )
+  }
+
+  if (challengeToken) {
+    return (
+      <div className="login-page">
+        <div className="login-background">
+          <div className="login-container">
+            <div className="login-header">
+              <div className="logo">
+                <h1>E-Commerce</h1>
+              </div>
+              <p className="login-subtitle">Enter the code from your authenticator app, or one of your recovery codes.</p>
+            </div>
+
+            <form onSubmit={handleTwoFactorSubmit} className="login-form">
+              <div className="form-group">
+                <label htmlFor="twoFactorCode">
+                  Authentication code
+                </label>
+                <input
+                  type="text"
+                  id="twoFactorCode"
+                  name="twoFactorCode"
+                  value={twoFactorCode}
+                  onChange={(e) => setTwoFactorCode(e.target.value)}
+                  placeholder="123456"
+                  autoComplete="one-time-code"
+                  autoFocus
+                  required
+                />
+              </div>
+
+              <button
+                type="submit"
+                className="btn btn-primary login-btn"
+                disabled={loading}
+              >
+                {loading ? (
+                  <>
+                    <span className="loading-spinner"></span>
+                    Loading...
+                  </>
+                ) : 'Verify'}
+              </button>
+            </form>
+
+            <div className="login-footer">
+              <button
+                type="button"
+                className="btn btn-secondary switch-btn"
+                onClick={handleCancelTwoFactor}
+              >
+                ← Back to Login
+              </button>
+            </div>
+          </div>
+        </div>
+      </div>
+    )
+  }
+
   return (
     <div className="login-page">
       <div className="login-background">
@@ -66,6 +232,23 @@ function LoginScreen({ onLogin, showToast }) {
               />
             </div>
             
+            {isSignup && (
+              <div className="form-group">
+                <label htmlFor="email">
+                  Email
+                </label>
+                <input
+                  type="email"
+                  id="email"
+                  name="email"
+                  value={formData.email}
+                  onChange={handleInputChange}
+                  placeholder="Enter your email"
+                  required
+                />
+              </div>
+            )}
+            
             <div className="form-group">
               <label htmlFor="password">
                 Password
@@ -107,6 +290,15 @@ function LoginScreen({ onLogin, showToast }) {
             >
               {isSignup ? '← Back to Login' : 'Create New Account'}
             </button>
+            {!isSignup && (
+              <button
+                type="button"
+                className="btn btn-secondary switch-btn"
+                onClick={() => setIsVerifying(true)}
+              >
+                Verify Email
+              </button>
+            )}
           </div>
         </div>
       </div>
@@ -1,6 +1,10 @@
 import React from 'react'
+import { formatMoney } from '../utils/money'
 
-function OrderHistoryModal({ orders, isOpen, onClose }) {
+// Customers may cancel orders until fulfilment starts
+const CANCELLABLE_STATUSES = ['pending_payment', 'paid']
+
+function OrderHistoryModal({ orders, isOpen, onClose, onCancelOrder }) {
   if (!isOpen) return null
 
   return (
@@ -24,16 +28,27 @@ function OrderHistoryModal({ orders, isOpen, onClose }) {
                       Created: {new Date(order.created_at).toLocaleString()}
                     </p>
                     <p className="order-cart-id">Cart ID: {order.cart_id}</p>
+                    <p className="order-status">Status: {order.status?.replace(/_/g, ' ')}</p>
+                    <p className="order-total">Total: {formatMoney(order.total)}</p>
+                    {order.cancel_reason && (
+                      <p className="order-cancel-reason">Cancelled: {order.cancel_reason}</p>
+                    )}
+                    {onCancelOrder && CANCELLABLE_STATUSES.includes(order.status) && (
+                      <button className="btn btn-secondary" onClick={() => onCancelOrder(order.id)}>
+                        Cancel Order
+                      </button>
+                    )}
                   </div>
                   
-                  {order.cart && order.cart.items && order.cart.items.length > 0 ? (
+                  {order.items && order.items.length > 0 ? (
                     <div className="order-items">
                       <h4>Items:</h4>
                       <ul>
-                        {order.cart.items.map((item) => (
-                          <li key={item.id} className="order-item-detail">
+                        {order.items.map((item) => (
+                          <li key={item.item_id} className="order-item-detail">
                             <span className="item-name">{item.name}</span>
-                            <span className="item-status">Status: {item.status}</span>
+                            <span className="item-quantity">Qty: {item.quantity}</span>
+                            <span className="item-price">{formatMoney(item.line_total)}</span>
                           </li>
                         ))}
                       </ul>
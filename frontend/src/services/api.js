@@ -22,7 +22,9 @@ const apiRequest = async (endpoint, options = {}) => {
   
   if (!response.ok) {
     const errorData = await response.json().catch(() => ({}))
-    throw new Error(errorData.error || `HTTP error! status: ${response.status}`)
+    const error = new Error(errorData.error || `HTTP error! status: ${response.status}`)
+    error.code = errorData.code
+    throw error
   }
   
   return response.json()
@@ -43,6 +45,31 @@ export const loginUser = async (credentials) => {
   })
 }
 
+export const loginTwoFactor = async (challengeToken, code) => {
+  // Authenticator codes are digits only; anything else is treated as a recovery code
+  const isTotp = /^\d{6}$/.test(code)
+  return apiRequest('/users/login/2fa', {
+    method: 'POST',
+    body: JSON.stringify({
+      challenge_token: challengeToken,
+      ...(isTotp ? { code } : { recovery_code: code })
+    })
+  })
+}
+
+export const verifyEmail = async (token) => {
+  return apiRequest('/users/email/verify', {
+    method: 'POST',
+    body: JSON.stringify({ token })
+  })
+}
+
+export const resendVerification = async () => {
+  return apiRequest('/users/me/email/resend', {
+    method: 'POST'
+  })
+}
+
 export const getUsers = async () => {
   return apiRequest('/users')
 }
@@ -96,6 +123,13 @@ export const createOrder = async (cartId) => {
   })
 }
 
+export const cancelOrder = async (orderId, reason) => {
+  return apiRequest(`/orders/${orderId}/cancel`, {
+    method: 'POST',
+    body: JSON.stringify({ reason })
+  })
+}
+
 export const getUserOrders = async () => {
   return apiRequest('/orders/my')
 }
@@ -0,0 +1,22 @@
+// Prices travel as { amount, currency } with amount in the currency's minor unit (e.g. cents)
+
+const fractionDigits = (currency) =>
+  new Intl.NumberFormat(undefined, { style: 'currency', currency }).resolvedOptions().maximumFractionDigits
+
+// Format a money value for display, e.g. { amount: 1999, currency: 'USD' } -> "$19.99"
+export const formatMoney = (money) => {
+  if (!money || !money.currency) return ''
+  const digits = fractionDigits(money.currency)
+  return new Intl.NumberFormat(undefined, { style: 'currency', currency: money.currency })
+    .format(money.amount / 10 ** digits)
+}
+
+// Convert a decimal string such as "19.99" to integer minor units without floating-point rounding
+export const toMinorUnits = (value, currency) => {
+  const digits = fractionDigits(currency)
+  const match = String(value).trim().match(/^(\d+)(?:\.(\d*))?$/)
+  if (!match) return null
+  const fraction = (match[2] || '')
+  if (fraction.length > digits) return null
+  return parseInt(match[1] + fraction.padEnd(digits, '0'), 10)
+}